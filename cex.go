@@ -0,0 +1,458 @@
+package gocite
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CEXOptions sets the delimiters used in a CEX file.
+// Empty fields fall back to the defaults "#" and ",".
+// ImageLinks makes ParseCEX keep the relations whose subject is a Passage of the file
+// as the ImageLinks of the Passage instead of in CEXData.Relations, e.g. to read back
+// the ImageLinks WriteCEX has written. WriteCEX ignores it.
+type CEXOptions struct {
+	Delimiter    string
+	SubDelimiter string
+	ImageLinks   bool
+}
+
+// CEXData is the content of a CEX (CITE Exchange) file as described in
+// https://cite-architecture.github.io/citedx/CEX-spec-3.0.1/
+// Relations holds all relations of the file, or only those whose subject is not a Passage
+// of the file if they were read with CEXOptions.ImageLinks.
+type CEXData struct {
+	Version     string
+	Library     CiteLibrary
+	Catalog     []CatalogEntry
+	Textgroups  []Textgroup
+	Collections []CiteCollection
+	Relations   []Triple
+	DataModels  []DataModel
+}
+
+// CiteLibrary is the content of a #!citelibrary block
+type CiteLibrary struct {
+	Name, URN, License string
+	Namespaces         []Namespace
+}
+
+// Namespace maps a CTS or CITE2 namespace abbreviation to its URI
+type Namespace struct {
+	Abbreviation, URI string
+}
+
+// CatalogEntry describes a version or exemplar in a #!ctscatalog block
+type CatalogEntry struct {
+	URN            string
+	CitationScheme string
+	GroupName      string
+	WorkTitle      string
+	VersionLabel   string
+	ExemplarLabel  string
+	Online         bool
+	Language       string
+}
+
+// CiteCollection is a collection declared in a #!citecollections block,
+// together with its #!citeproperties and the objects of its #!citedata.
+// Header holds the column names of the #!citedata block in their original order.
+type CiteCollection struct {
+	URN               string
+	Description       string
+	LabellingProperty string
+	OrderingProperty  string
+	License           string
+	Properties        []CiteProperty
+	Header            []string
+	Objects           []CiteObject
+}
+
+// CiteProperty is a property declared in a #!citeproperties block
+type CiteProperty struct {
+	URN, Label, Type string
+	Authority        []string
+}
+
+// CiteObject is a row of a #!citedata block.
+// Properties maps the column names of the block to the values of the object.
+type CiteObject struct {
+	URN        string
+	Properties map[string]string
+}
+
+// DataModel is a row of a #!datamodels block
+type DataModel struct {
+	Collection, Model, Label, Description string
+}
+
+// CEXError reports a problem in a CEX file along with the line it occurred in
+type CEXError struct {
+	Line  int
+	Block string
+	Err   error
+}
+
+func (e *CEXError) Error() string {
+	if e.Block == "" {
+		return fmt.Sprintf("cex line %d: %s", e.Line, e.Err)
+	}
+	return fmt.Sprintf("cex line %d (#!%s): %s", e.Line, e.Block, e.Err)
+}
+
+// Unwrap returns the underlying error, e.g. a *URNError
+func (e *CEXError) Unwrap() error {
+	return e.Err
+}
+
+// cexBlocks lists the block labels a CEX file may contain
+var cexBlocks = []string{"cexversion", "citelibrary", "ctscatalog", "ctsdata", "citecollections",
+	"citeproperties", "citedata", "relations", "datamodels"}
+
+// withDefaults fills empty delimiters with their defaults
+func (opts CEXOptions) withDefaults() CEXOptions {
+	if opts.Delimiter == "" {
+		opts.Delimiter = "#"
+	}
+	if opts.SubDelimiter == "" {
+		opts.SubDelimiter = ","
+	}
+	return opts
+}
+
+// ReadCEX reads a CEX file using the default delimiters
+func ReadCEX(r io.Reader) (CEXData, error) {
+	return ParseCEX(r, CEXOptions{})
+}
+
+// ParseCEX reads a CEX file with the given delimiters.
+// Texts of the #!ctsdata block are grouped into ordered Works with linked Passages,
+// which are in turn grouped into Textgroups; the text of each Passage is stored
+// as its "txt" Tokenisation. The relations of the #!relations block are kept in Relations,
+// see CEXOptions.ImageLinks. Errors are returned as *CEXError.
+func ParseCEX(r io.Reader, opts CEXOptions) (CEXData, error) {
+	opts = opts.withDefaults()
+	p := cexParser{opts: opts, workIndex: map[string]int{}, passageIDs: map[string]bool{}}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	block := ""
+	header := false
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case strings.TrimSpace(line) == "", strings.HasPrefix(line, "//"):
+			continue
+		case strings.HasPrefix(line, "#!"):
+			block = strings.TrimSpace(strings.TrimPrefix(line, "#!"))
+			if !contains(cexBlocks, block) {
+				return CEXData{}, &CEXError{Line: lineNumber, Err: errors.New("unknown block #!" + block)}
+			}
+			header = block == "ctscatalog" || block == "citecollections" || block == "citeproperties" ||
+				block == "citedata" || block == "datamodels"
+			p.citedataHeader = nil
+			continue
+		case block == "":
+			return CEXData{}, &CEXError{Line: lineNumber, Err: errors.New("content outside of a block")}
+		}
+		var err error
+		if header {
+			header = false
+			if block == "citedata" {
+				err = p.readCitedataHeader(line)
+			}
+		} else {
+			err = p.readLine(block, line)
+		}
+		if err != nil {
+			return CEXData{}, &CEXError{Line: lineNumber, Block: block, Err: err}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return CEXData{}, &CEXError{Line: lineNumber + 1, Block: block, Err: err}
+	}
+	return p.result(), nil
+}
+
+// cexParser collects the content of a CEX file while it is being read
+type cexParser struct {
+	opts           CEXOptions
+	data           CEXData
+	works          []Work
+	workIndex      map[string]int
+	passageIDs     map[string]bool
+	citedataHeader []string
+	urnColumn      int
+}
+
+// readLine reads a single line of content of the given block
+func (p *cexParser) readLine(block, line string) error {
+	switch block {
+	case "cexversion":
+		if p.data.Version != "" {
+			return errors.New("more than one version")
+		}
+		p.data.Version = strings.TrimSpace(line)
+	case "citelibrary":
+		return p.readLibrary(line)
+	case "ctscatalog":
+		return p.readCatalog(line)
+	case "ctsdata":
+		return p.readText(line)
+	case "citecollections":
+		cols, err := p.columns(line, 5, 5)
+		if err != nil {
+			return err
+		}
+		if _, err := ParseCITEURN(cols[0]); err != nil {
+			return err
+		}
+		p.data.Collections = append(p.data.Collections, CiteCollection{URN: cols[0], Description: cols[1],
+			LabellingProperty: cols[2], OrderingProperty: cols[3], License: cols[4]})
+	case "citeproperties":
+		return p.readProperty(line)
+	case "citedata":
+		return p.readObject(line)
+	case "relations":
+		cols, err := p.columns(line, 3, 3)
+		if err != nil {
+			return err
+		}
+		p.data.Relations = append(p.data.Relations, Triple{Subject: cols[0], Verb: cols[1], Object: cols[2]})
+	case "datamodels":
+		cols, err := p.columns(line, 4, 4)
+		if err != nil {
+			return err
+		}
+		p.data.DataModels = append(p.data.DataModels, DataModel{Collection: cols[0], Model: cols[1], Label: cols[2], Description: cols[3]})
+	}
+	return nil
+}
+
+// columns splits a line by the delimiter and checks the number of columns
+func (p *cexParser) columns(line string, minCols, maxCols int) ([]string, error) {
+	cols := strings.Split(line, p.opts.Delimiter)
+	if len(cols) < minCols || len(cols) > maxCols {
+		if minCols == maxCols {
+			return nil, fmt.Errorf("expected %d columns, found %d", minCols, len(cols))
+		}
+		return nil, fmt.Errorf("expected %d to %d columns, found %d", minCols, maxCols, len(cols))
+	}
+	return cols, nil
+}
+
+// readLibrary reads a key-value line of a #!citelibrary block
+func (p *cexParser) readLibrary(line string) error {
+	cols := strings.Split(line, p.opts.Delimiter)
+	switch {
+	case cols[0] == "namespace" && len(cols) == 3:
+		p.data.Library.Namespaces = append(p.data.Library.Namespaces, Namespace{Abbreviation: cols[1], URI: cols[2]})
+	case len(cols) != 2:
+		return fmt.Errorf("expected 2 columns, found %d", len(cols))
+	case cols[0] == "name":
+		p.data.Library.Name = cols[1]
+	case cols[0] == "urn":
+		p.data.Library.URN = cols[1]
+	case cols[0] == "license":
+		p.data.Library.License = cols[1]
+	default:
+		return errors.New("unknown key " + cols[0])
+	}
+	return nil
+}
+
+// readCatalog reads a line of a #!ctscatalog block
+func (p *cexParser) readCatalog(line string) error {
+	cols, err := p.columns(line, 7, 8)
+	if err != nil {
+		return err
+	}
+	if _, err := ParseCTSURN(cols[0]); err != nil {
+		return err
+	}
+	online, err := strconv.ParseBool(cols[6])
+	if err != nil {
+		return errors.New("online must be true or false, found " + cols[6])
+	}
+	entry := CatalogEntry{URN: cols[0], CitationScheme: cols[1], GroupName: cols[2], WorkTitle: cols[3],
+		VersionLabel: cols[4], ExemplarLabel: cols[5], Online: online}
+	if len(cols) == 8 {
+		entry.Language = cols[7]
+	}
+	p.data.Catalog = append(p.data.Catalog, entry)
+	return nil
+}
+
+// readText reads a line of a #!ctsdata block and appends the passage
+// to the end of its Work
+func (p *cexParser) readText(line string) error {
+	cols := strings.SplitN(line, p.opts.Delimiter, 2)
+	if len(cols) != 2 {
+		return errors.New("expected urn and text")
+	}
+	urn, err := ParseCTSURN(cols[0])
+	if err != nil {
+		return err
+	}
+	if urn.Passage == "" {
+		return errors.New("urn " + cols[0] + " has no passage")
+	}
+	if p.passageIDs[cols[0]] {
+		return errors.New("duplicate passage " + cols[0])
+	}
+	p.passageIDs[cols[0]] = true
+	workID := urn.DropPassage().String()
+	index, found := p.workIndex[workID]
+	if !found {
+		index = len(p.works)
+		p.workIndex[workID] = index
+		p.works = append(p.works, Work{WorkID: workID, Ordered: true, idIndex: &passageIndex{ids: map[string]int{}, layers: 1}})
+	}
+	work := &p.works[index]
+	passage := Passage{PassageID: cols[0],
+		Range:    urn.Range,
+		Analysis: []Tokenisation{NewTextTokenisation(cols[1])},
+		Index:    len(work.Passages)}
+	passloc := PassLoc{Exists: true, PassageID: passage.PassageID, Index: passage.Index}
+	if work.Last.Exists {
+		passage.Prev = work.Last
+		work.Passages[work.Last.Index].Next = passloc
+	} else {
+		work.First = passloc
+	}
+	work.Last = passloc
+	work.idIndex.ids[passage.PassageID] = passage.Index
+	work.Passages = append(work.Passages, passage)
+	return nil
+}
+
+// readProperty reads a line of a #!citeproperties block and adds the property to its collection
+func (p *cexParser) readProperty(line string) error {
+	cols, err := p.columns(line, 3, 4)
+	if err != nil {
+		return err
+	}
+	urn, err := ParseCITEURN(cols[0])
+	if err != nil {
+		return err
+	}
+	property := CiteProperty{URN: cols[0], Label: cols[1], Type: cols[2]}
+	if len(cols) == 4 && cols[3] != "" {
+		property.Authority = strings.Split(cols[3], p.opts.SubDelimiter)
+	}
+	collection := p.collection(urn.DropProperty().DropObject().String())
+	collection.Properties = append(collection.Properties, property)
+	return nil
+}
+
+// readCitedataHeader reads the header line of a #!citedata block,
+// which names the columns of the block; the "urn" column identifies the objects
+func (p *cexParser) readCitedataHeader(line string) error {
+	p.citedataHeader = strings.Split(line, p.opts.Delimiter)
+	p.urnColumn = -1
+	for i, name := range p.citedataHeader {
+		if strings.EqualFold(name, "urn") {
+			p.urnColumn = i
+		}
+	}
+	if p.urnColumn == -1 {
+		return errors.New("header has no urn column")
+	}
+	return nil
+}
+
+// readObject reads a line of a #!citedata block and adds the object to its collection
+func (p *cexParser) readObject(line string) error {
+	cols, err := p.columns(line, len(p.citedataHeader), len(p.citedataHeader))
+	if err != nil {
+		return err
+	}
+	urn, err := ParseCITEURN(cols[p.urnColumn])
+	if err != nil {
+		return err
+	}
+	object := CiteObject{URN: cols[p.urnColumn], Properties: map[string]string{}}
+	for i, name := range p.citedataHeader {
+		object.Properties[name] = cols[i]
+	}
+	collection := p.collection(urn.DropObject().String())
+	if collection.Header == nil {
+		collection.Header = p.citedataHeader
+	}
+	collection.Objects = append(collection.Objects, object)
+	return nil
+}
+
+// collection returns the collection with the given URN,
+// adding it if it has not been declared in a #!citecollections block
+func (p *cexParser) collection(collURN string) *CiteCollection {
+	for i := range p.data.Collections {
+		if p.data.Collections[i].URN == collURN {
+			return &p.data.Collections[i]
+		}
+	}
+	p.data.Collections = append(p.data.Collections, CiteCollection{URN: collURN})
+	return &p.data.Collections[len(p.data.Collections)-1]
+}
+
+// result moves the relations of Passages to their ImageLinks if asked to, groups the Works
+// by their textgroup, gives them the citation scheme of their CatalogEntry
+// and returns the collected CEXData
+func (p *cexParser) result() CEXData {
+	if p.opts.ImageLinks {
+		p.imageLinks()
+	}
+	groupIndex := map[string]int{}
+	catalog := TextRepository{Catalog: p.data.Catalog}
+	for _, work := range p.works {
+		work.idIndex.passages = work.Passages
+		work = buildCitationIndex(work)
+		if entry, found := catalog.GetCatalogEntry(work.WorkID); found {
+			work.CitationScheme = citationScheme(entry)
+		}
+		groupID := textgroupID(SplitCTS(work.WorkID))
+		index, found := groupIndex[groupID]
+		if !found {
+			index = len(p.data.Textgroups)
+			groupIndex[groupID] = index
+			p.data.Textgroups = append(p.data.Textgroups, Textgroup{TextgroupID: groupID})
+		}
+		p.data.Textgroups[index].Works = append(p.data.Textgroups[index].Works, work)
+	}
+	return p.data
+}
+
+// imageLinks moves the relations whose subject is a Passage of the file to its ImageLinks
+func (p *cexParser) imageLinks() {
+	var relations []Triple
+	for _, triple := range p.data.Relations {
+		if !p.passageIDs[triple.Subject] {
+			relations = append(relations, triple)
+			continue
+		}
+		work := &p.works[p.workIndex[SplitCTS(triple.Subject).DropPassage().String()]]
+		index := work.idIndex.ids[triple.Subject]
+		work.Passages[index].ImageLinks = append(work.Passages[index].ImageLinks, triple)
+	}
+	p.data.Relations = relations
+}
+
+// NewTextTokenisation returns the "txt" Tokenisation holding the text of a Passage
+func NewTextTokenisation(text string) Tokenisation {
+	return Tokenisation{ID: "txt", Description: "text", DataStructure: "string",
+		Array: ArrayToken{Type: "string", CharRepres: []string{text}}}
+}
+
+// PassageText returns the text stored in the "txt" Tokenisation of a Passage
+// along with a bool whether it has found one
+func PassageText(p Passage) (string, bool) {
+	index, found := FindTextTokens(p)
+	if !found {
+		return "", false
+	}
+	return strings.Join(p.Analysis[index].Array.CharRepres, ""), true
+}
@@ -0,0 +1,217 @@
+package gocite_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+var testCEX = `#!cexversion
+3.0
+
+#!citelibrary
+name#Test library
+urn#urn:cite2:cex:testlib.v1:1
+license#CC BY-SA
+namespace#greekLit#http://chs.harvard.edu/ctsns/greekLit
+
+// a comment
+#!ctscatalog
+urn#citationScheme#groupName#workTitle#versionLabel#exemplarLabel#online#lang
+urn:cts:greekLit:tlg0012.tlg001.msA:#book,line#Homeric epic#Iliad#HMT project diplomatic edition##true#grc
+urn:cts:greekLit:tlg0012.tlg002.msA:#book,line#Homeric epic#Odyssey#Test edition##true#grc
+
+#!ctsdata
+urn:cts:greekLit:tlg0012.tlg001.msA:1.1#Μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος
+urn:cts:greekLit:tlg0012.tlg001.msA:1.2#οὐλομένην, ἣ μυρί' Ἀχαιοῖς ἄλγε' ἔθηκε,
+urn:cts:greekLit:tlg0012.tlg001.msA:1.3#πολλὰς δ' ἰφθίμους ψυχὰς Ἄϊδι προΐαψεν
+urn:cts:greekLit:tlg0012.tlg002.msA:1.1#ἄνδρα μοι ἔννεπε
+
+#!citecollections
+URN#Description#Labelling property#Ordering property#License
+urn:cite2:hmt:msA.v1:#Pages of the Venetus A#urn:cite2:hmt:msA.v1.label:#urn:cite2:hmt:msA.v1.sequence:#CC BY 3.0
+
+#!citeproperties
+Property#Label#Type#Authority list
+urn:cite2:hmt:msA.v1.urn:#URN#Cite2Urn#
+urn:cite2:hmt:msA.v1.rv:#Recto or Verso#String#recto,verso
+
+#!citedata
+urn#sequence#rv
+urn:cite2:hmt:msA.v1:1r#1#recto
+urn:cite2:hmt:msA.v1:1v#2#verso
+
+#!relations
+urn:cts:greekLit:tlg0012.tlg001.msA:1.1#urn:cite2:cite:verbs.v1:illustratedBy#urn:cite2:hmt:vaimg.2017a:VA012RN_0013@0.1,0.2,0.3,0.4
+
+#!datamodels
+Collection#Model#Label#Description
+urn:cite2:hmt:vaimg.2017a:#urn:cite2:cite:datamodels.v1:imagemodel#Images#Images of the Venetus A
+`
+
+func TestReadCEX(t *testing.T) {
+	data, err := gocite.ReadCEX(strings.NewReader(testCEX))
+	if err != nil {
+		t.Fatal("Error calling ReadCEX: ", err)
+	}
+	if data.Version != "3.0" || data.Library.Name != "Test library" || len(data.Library.Namespaces) != 1 {
+		t.Error("unexpected version or library", data.Version, data.Library)
+	}
+	expectedCatalog := []gocite.CatalogEntry{{URN: "urn:cts:greekLit:tlg0012.tlg001.msA:", CitationScheme: "book,line",
+		GroupName: "Homeric epic", WorkTitle: "Iliad", VersionLabel: "HMT project diplomatic edition", Online: true, Language: "grc"}}
+	if !reflect.DeepEqual(data.Catalog[:1], expectedCatalog) {
+		t.Error("expected", expectedCatalog, "got", data.Catalog)
+	}
+	if len(data.Textgroups) != 1 || len(data.Textgroups[0].Works) != 2 {
+		t.Fatal("expected one textgroup with two works, got", data.Textgroups)
+	}
+	if data.Textgroups[0].TextgroupID != "urn:cts:greekLit:tlg0012:" {
+		t.Error("expected textgroup urn:cts:greekLit:tlg0012:, got", data.Textgroups[0].TextgroupID)
+	}
+	work := data.Textgroups[0].Works[0]
+	if work.WorkID != "urn:cts:greekLit:tlg0012.tlg001.msA:" || !work.Ordered || len(work.Passages) != 3 {
+		t.Fatal("unexpected work", work)
+	}
+	if work.First.PassageID != work.Passages[0].PassageID || work.Last.Index != 2 {
+		t.Error("unexpected First or Last", work.First, work.Last)
+	}
+	for i, p := range work.Passages {
+		if p.Index != i || p.Prev.Exists != (i > 0) || p.Next.Exists != (i < 2) {
+			t.Error("passage", i, "is not linked correctly:", p)
+		}
+		if i > 0 && p.Prev.PassageID != work.Passages[i-1].PassageID {
+			t.Error("passage", i, "expected Prev", work.Passages[i-1].PassageID, "got", p.Prev.PassageID)
+		}
+	}
+	text, _ := gocite.PassageText(work.Passages[0])
+	if text != "Μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος" {
+		t.Error("unexpected text", text)
+	}
+	if len(data.Collections) != 1 || len(data.Collections[0].Properties) != 2 || len(data.Collections[0].Objects) != 2 {
+		t.Fatal("unexpected collections", data.Collections)
+	}
+	if !reflect.DeepEqual(data.Collections[0].Properties[1].Authority, []string{"recto", "verso"}) {
+		t.Error("unexpected authority list", data.Collections[0].Properties[1].Authority)
+	}
+	if data.Collections[0].Objects[1].Properties["rv"] != "verso" {
+		t.Error("unexpected object", data.Collections[0].Objects[1])
+	}
+	if len(data.Relations) != 1 || len(data.Textgroups[0].Works[0].Passages[0].ImageLinks) != 0 || len(data.DataModels) != 1 {
+		t.Error("unexpected relations or data models", data.Relations, data.Textgroups[0].Works[0].Passages[0].ImageLinks, data.DataModels)
+	}
+}
+
+func TestParseCEXDelimiters(t *testing.T) {
+	cex := "#!ctsdata\nurn:cts:greekLit:tlg0012.tlg001.msA:1.1|Μῆνιν ἄειδε\n"
+	data, err := gocite.ParseCEX(strings.NewReader(cex), gocite.CEXOptions{Delimiter: "|"})
+	if err != nil {
+		t.Fatal("Error calling ParseCEX: ", err)
+	}
+	text, _ := gocite.PassageText(data.Textgroups[0].Works[0].Passages[0])
+	if text != "Μῆνιν ἄειδε" {
+		t.Error("unexpected text", text)
+	}
+}
+
+func TestReadCEXErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		line  int
+	}{
+		{input: "#!ctsdata\nurn:cts:greekLit:tlg0012.tlg001.msA:1.1#a\nurn:cts:greekLit:tlg0012.tlg001.msA:1.1#b\n", line: 3},
+		{input: "#!ctsdata\n\nurn:cts:greekLit:tlg0012.tlg001.msA:1-2-3#a\n", line: 3},
+		{input: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1#a\n", line: 1},
+		{input: "#!cexversion\n3.0\n#!unknown\n", line: 3},
+		{input: "#!relations\na#b\n", line: 2},
+	}
+	for _, test := range tests {
+		_, err := gocite.ReadCEX(strings.NewReader(test.input))
+		var cexErr *gocite.CEXError
+		if !errors.As(err, &cexErr) {
+			t.Error("For", test.input, "expected *CEXError, got", err)
+			continue
+		}
+		if cexErr.Line != test.line {
+			t.Error("For", test.input, "expected error in line", test.line, "got", cexErr)
+		}
+	}
+}
+
+func TestWriteCEX(t *testing.T) {
+	data, err := gocite.ReadCEX(strings.NewReader(testCEX))
+	if err != nil {
+		t.Fatal("Error calling ReadCEX: ", err)
+	}
+	var first strings.Builder
+	if err := gocite.WriteCEX(&first, data, gocite.CEXOptions{}); err != nil {
+		t.Fatal("Error calling WriteCEX: ", err)
+	}
+	reread, err := gocite.ReadCEX(strings.NewReader(first.String()))
+	if err != nil {
+		t.Fatal("Error reading written CEX: ", err, first.String())
+	}
+	if !reflect.DeepEqual(data, reread) {
+		t.Error("expected", data, "got", reread)
+	}
+	var second strings.Builder
+	if err := gocite.WriteCEX(&second, reread, gocite.CEXOptions{}); err != nil {
+		t.Fatal("Error calling WriteCEX: ", err)
+	}
+	if first.String() != second.String() {
+		t.Error("expected", first.String(), "got", second.String())
+	}
+}
+
+func TestWriteCEXImageLinks(t *testing.T) {
+	workID := "urn:cts:greekLit:tlg0012.tlg001.msA:"
+	link := gocite.Triple{Subject: workID + "1.2", Verb: "urn:cite2:cite:verbs.v1:illustratedBy", Object: "urn:cite2:hmt:vaimg.2017a:VA012RN_0013@0.1,0.2,0.3,0.4"}
+	work, err := gocite.InsertPassagesAfter("", []gocite.Passage{
+		{PassageID: workID + "1.1", Analysis: []gocite.Tokenisation{gocite.NewTextTokenisation("Μῆνιν ἄειδε θεὰ")}},
+		{PassageID: workID + "1.2", Analysis: []gocite.Tokenisation{gocite.NewTextTokenisation("οὐλομένην")}, ImageLinks: []gocite.Triple{link}},
+	}, gocite.Work{WorkID: workID})
+	if err != nil {
+		t.Fatal("Error calling InsertPassagesAfter: ", err)
+	}
+	data := gocite.CEXData{Textgroups: []gocite.Textgroup{{TextgroupID: "urn:cts:greekLit:tlg0012:", Works: []gocite.Work{work}}}}
+	var b strings.Builder
+	if err := gocite.WriteCEX(&b, data, gocite.CEXOptions{}); err != nil {
+		t.Fatal("Error calling WriteCEX: ", err)
+	}
+	reread, err := gocite.ParseCEX(strings.NewReader(b.String()), gocite.CEXOptions{ImageLinks: true})
+	if err != nil {
+		t.Fatal("Error reading written CEX: ", err, b.String())
+	}
+	if !reflect.DeepEqual(data.Textgroups, reread.Textgroups) || len(reread.Relations) != 0 {
+		t.Error("expected", data.Textgroups, "got", reread.Textgroups, reread.Relations)
+	}
+}
+
+func TestWriteCEXDocumentOrder(t *testing.T) {
+	work := newTestcorpus5
+	work.Passages = append([]gocite.Passage{}, work.Passages...)
+	link := gocite.Triple{Subject: work.Passages[2].PassageID, Verb: "urn:cite2:cite:verbs.v1:illustratedBy", Object: "urn:cite2:hmt:vaimg.2017a:VA012RN_0013"}
+	work.Passages[2].ImageLinks = []gocite.Triple{link}
+	data := gocite.CEXData{Textgroups: []gocite.Textgroup{{TextgroupID: "urn:cts:collection:workgroup:", Works: []gocite.Work{work}}}}
+	var b strings.Builder
+	if err := gocite.WriteCEX(&b, data, gocite.CEXOptions{Delimiter: "|"}); err != nil {
+		t.Fatal("Error calling WriteCEX: ", err)
+	}
+	expected := `#!ctscatalog
+urn|citationScheme|groupName|workTitle|versionLabel|exemplarLabel|online|lang
+urn:cts:collection:workgroup.work:||||||true|
+
+#!ctsdata
+urn:cts:collection:workgroup.work:1|This is the first node.
+urn:cts:collection:workgroup.work:2-4|This is. the second. node.
+urn:cts:collection:workgroup.work:5|This is the third node.
+
+#!relations
+urn:cts:collection:workgroup.work:2-4|urn:cite2:cite:verbs.v1:illustratedBy|urn:cite2:hmt:vaimg.2017a:VA012RN_0013
+`
+	if b.String() != expected {
+		t.Error("expected", expected, "got", b.String())
+	}
+}
@@ -0,0 +1,222 @@
+package gocite
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteCEX writes CEXData as a CEX file with the given delimiters.
+// The #!ctsdata block lists the Passages of every Work in document order,
+// following the Next links from Work.First rather than the order of Work.Passages.
+// Works without a CatalogEntry get a minimal one in the #!ctscatalog block,
+// and the ImageLinks of all Passages are added to the #!relations block.
+// Reading the result with ParseCEX and the same options yields the same CEXData.
+func WriteCEX(w io.Writer, data CEXData, opts CEXOptions) error {
+	opts = opts.withDefaults()
+	cw := cexWriter{w: bufio.NewWriter(w), opts: opts}
+	texts, imageLinks, err := cw.texts(data.Textgroups)
+	if err != nil {
+		return err
+	}
+	if data.Version != "" {
+		cw.block("cexversion")
+		cw.line(data.Version)
+	}
+	if data.Library.Name != "" || data.Library.URN != "" || data.Library.License != "" || len(data.Library.Namespaces) > 0 {
+		cw.block("citelibrary")
+		for _, pair := range [][2]string{{"name", data.Library.Name}, {"urn", data.Library.URN}, {"license", data.Library.License}} {
+			if pair[1] != "" {
+				cw.line(pair[0], pair[1])
+			}
+		}
+		for _, ns := range data.Library.Namespaces {
+			cw.line("namespace", ns.Abbreviation, ns.URI)
+		}
+	}
+	catalog := catalogForWorks(data.Catalog, data.Textgroups)
+	if len(catalog) > 0 {
+		cw.block("ctscatalog")
+		cw.line("urn", "citationScheme", "groupName", "workTitle", "versionLabel", "exemplarLabel", "online", "lang")
+		for _, entry := range catalog {
+			cw.line(entry.URN, entry.CitationScheme, entry.GroupName, entry.WorkTitle,
+				entry.VersionLabel, entry.ExemplarLabel, strconv.FormatBool(entry.Online), entry.Language)
+		}
+	}
+	if len(texts) > 0 {
+		cw.block("ctsdata")
+		for _, text := range texts {
+			cw.line(text.ID, text.Text)
+		}
+	}
+	cw.collections(data.Collections)
+	relations := append([]Triple{}, data.Relations...)
+	for _, link := range imageLinks {
+		if !containsTriple(relations, link) {
+			relations = append(relations, link)
+		}
+	}
+	if len(relations) > 0 {
+		cw.block("relations")
+		for _, triple := range relations {
+			cw.line(triple.Subject, triple.Verb, triple.Object)
+		}
+	}
+	if len(data.DataModels) > 0 {
+		cw.block("datamodels")
+		cw.line("Collection", "Model", "Label", "Description")
+		for _, model := range data.DataModels {
+			cw.line(model.Collection, model.Model, model.Label, model.Description)
+		}
+	}
+	if cw.err != nil {
+		return cw.err
+	}
+	return cw.w.Flush()
+}
+
+// cexWriter writes the blocks of a CEX file and keeps the first write error
+type cexWriter struct {
+	w       *bufio.Writer
+	opts    CEXOptions
+	started bool
+	err     error
+}
+
+// block starts a new block, separated from the previous one by an empty line
+func (cw *cexWriter) block(label string) {
+	if cw.started {
+		cw.write("\n")
+	}
+	cw.started = true
+	cw.write("#!" + label + "\n")
+}
+
+// line writes the columns of a line joined by the delimiter
+func (cw *cexWriter) line(cols ...string) {
+	cw.write(strings.Join(cols, cw.opts.Delimiter) + "\n")
+}
+
+func (cw *cexWriter) write(s string) {
+	if cw.err == nil {
+		_, cw.err = cw.w.WriteString(s)
+	}
+}
+
+// texts collects the text of all Works in document order along with the ImageLinks of their Passages
+func (cw *cexWriter) texts(textgroups []Textgroup) ([]TextAndID, []Triple, error) {
+	texts := []TextAndID{}
+	imageLinks := []Triple{}
+	for _, textgroup := range textgroups {
+		for _, work := range textgroup.Works {
+			passages, err := documentOrder(work)
+			if err != nil {
+				return nil, nil, errors.New("WriteCEX: " + err.Error())
+			}
+			for _, p := range passages {
+				text, found := PassageText(p)
+				if !found {
+					return nil, nil, errors.New("WriteCEX: txt not found in Passage " + p.PassageID)
+				}
+				if strings.ContainsAny(text, "\r\n") || strings.Contains(p.PassageID, cw.opts.Delimiter) {
+					return nil, nil, errors.New("WriteCEX: Passage " + p.PassageID + " cannot be written on a single line")
+				}
+				texts = append(texts, TextAndID{ID: p.PassageID, Text: text})
+				imageLinks = append(imageLinks, p.ImageLinks...)
+			}
+		}
+	}
+	return texts, imageLinks, nil
+}
+
+// collections writes the #!citecollections, #!citeproperties and #!citedata blocks
+func (cw *cexWriter) collections(collections []CiteCollection) {
+	if len(collections) == 0 {
+		return
+	}
+	cw.block("citecollections")
+	cw.line("URN", "Description", "Labelling property", "Ordering property", "License")
+	for _, coll := range collections {
+		cw.line(coll.URN, coll.Description, coll.LabellingProperty, coll.OrderingProperty, coll.License)
+	}
+	properties := []CiteProperty{}
+	for _, coll := range collections {
+		properties = append(properties, coll.Properties...)
+	}
+	if len(properties) > 0 {
+		cw.block("citeproperties")
+		cw.line("Property", "Label", "Type", "Authority list")
+		for _, property := range properties {
+			cw.line(property.URN, property.Label, property.Type, strings.Join(property.Authority, cw.opts.SubDelimiter))
+		}
+	}
+	for _, coll := range collections {
+		if len(coll.Objects) == 0 {
+			continue
+		}
+		header := coll.Header
+		if header == nil {
+			header = objectHeader(coll.Objects)
+		}
+		cw.block("citedata")
+		cw.line(header...)
+		for _, object := range coll.Objects {
+			cols := make([]string, len(header))
+			for i, name := range header {
+				cols[i] = object.Properties[name]
+				if strings.EqualFold(name, "urn") {
+					cols[i] = object.URN
+				}
+			}
+			cw.line(cols...)
+		}
+	}
+}
+
+// objectHeader returns "urn" followed by the sorted property names used by objects
+func objectHeader(objects []CiteObject) []string {
+	names := []string{}
+	for _, object := range objects {
+		for name := range object.Properties {
+			if !strings.EqualFold(name, "urn") && !contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return append([]string{"urn"}, names...)
+}
+
+// catalogForWorks returns the catalog followed by minimal entries
+// for all Works that have no CatalogEntry yet
+func catalogForWorks(catalog []CatalogEntry, textgroups []Textgroup) []CatalogEntry {
+	result := append([]CatalogEntry{}, catalog...)
+	for _, textgroup := range textgroups {
+		for _, work := range textgroup.Works {
+			found := false
+			for _, entry := range result {
+				if entry.URN == work.WorkID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				result = append(result, CatalogEntry{URN: work.WorkID, CitationScheme: strings.Join(work.CitationScheme, ","), Online: true})
+			}
+		}
+	}
+	return result
+}
+
+// containsTriple returns true if the triple is found in the triples slice
+func containsTriple(triples []Triple, triple Triple) bool {
+	for _, t := range triples {
+		if t == triple {
+			return true
+		}
+	}
+	return false
+}
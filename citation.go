@@ -0,0 +1,263 @@
+package gocite
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CitationDepth returns the number of citation levels of a Work:
+// the length of its CitationScheme if it has one, or else the deepest citation of its Passages
+func CitationDepth(work Work) int {
+	if len(work.CitationScheme) > 0 {
+		return len(work.CitationScheme)
+	}
+	depth := 0
+	for i := range work.Passages {
+		if work.Passages[i].PassageID == "" {
+			continue
+		}
+		if d := len(SplitCTS(work.Passages[i].PassageID).Start.Citation); d > depth {
+			depth = d
+		}
+	}
+	return depth
+}
+
+// CitationLevel returns the depth of a level of the CitationScheme of a Work,
+// e.g. 1 for "book" in a Work cited by book and line, along with a bool whether it has found the level
+func CitationLevel(level string, work Work) (int, bool) {
+	for i := range work.CitationScheme {
+		if work.CitationScheme[i] == level {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// GetValidReff returns the CTS URNs of the citation nodes at the given depth
+// within a CTS URN in document order, e.g. the books of a Work cited by book and line at depth 1.
+// The URN may be a Work, a citation node or a range. Depth 0 returns the URNs of the Passages
+// themselves, Passages cited with fewer levels than depth are returned as they are.
+func GetValidReff(ctsID string, depth int, work Work) ([]string, error) {
+	if depth < 0 || depth > CitationDepth(work) {
+		return []string{}, errors.New("GetValidReff: invalid depth " + strconv.Itoa(depth) + " for Work " + work.WorkID)
+	}
+	passages, err := passagesByID(ctsID, work)
+	if err != nil {
+		return []string{}, err
+	}
+	reff := []string{}
+	for _, p := range passages {
+		id := p.PassageID
+		if depth > 0 {
+			id = cutToDepth(SplitCTS(p.PassageID), depth)
+		}
+		if len(reff) == 0 || reff[len(reff)-1] != id {
+			reff = append(reff, id)
+		}
+	}
+	return reff, nil
+}
+
+// GetLeafPassages returns the Passages contained by the citation node of a CTS URN in document order,
+// e.g. all lines of book 1 for urn:cts:greekLit:tlg0012.tlg001.msA:1 in a Work cited by book and line
+func GetLeafPassages(ctsID string, work Work) ([]Passage, error) {
+	urn := SplitCTS(ctsID).DropSubref()
+	if urn.InValid || urn.Passage == "" || urn.Range {
+		return []Passage{}, errors.New("GetLeafPassages: " + ctsID + " is not a citation node")
+	}
+	if leaves, found := indexedLeaves(urn, work); found {
+		return leaves, nil
+	}
+	all, err := documentOrder(work)
+	if err != nil {
+		return []Passage{}, err
+	}
+	leaves := []Passage{}
+	for _, p := range all {
+		if SplitCTS(p.PassageID).IsContainedBy(urn) {
+			leaves = append(leaves, p)
+		}
+	}
+	if len(leaves) == 0 {
+		return leaves, errors.New("GetLeafPassages: no Passages found for " + ctsID)
+	}
+	return leaves, nil
+}
+
+// leafSpan holds the PassageIDs of the first and last Passage a citation node contains
+type leafSpan struct {
+	first, last string
+}
+
+// citationIndex maps the citation nodes of the Passages of a Work and all their containers
+// to the first and last Passage in document order they contain. It is built on first use
+// from the Passages slice it was created for and not used for any other.
+type citationIndex struct {
+	once     sync.Once
+	passages []Passage
+	spans    map[string]leafSpan
+}
+
+// buildCitationIndex returns the Work with a new citation index of its Passages, see citationIndex.
+// Works with broken links or with Passages citing ranges or subreferences get no spans,
+// GetLeafPassages searches them linearly.
+func buildCitationIndex(work Work) Work {
+	work.citations = &citationIndex{passages: work.Passages}
+	return work
+}
+
+// span returns the leafSpan of a citation node of work, building the index on first use,
+// along with a bool whether the index has the node and was created for the Passages of work
+func (index *citationIndex) span(node string, work Work) (leafSpan, bool) {
+	if index == nil || len(index.passages) != len(work.Passages) || len(work.Passages) == 0 || &index.passages[0] != &work.Passages[0] {
+		return leafSpan{}, false
+	}
+	index.once.Do(func() {
+		index.spans = citationSpans(work)
+	})
+	span, found := index.spans[node]
+	return span, found
+}
+
+// citationSpans returns the leafSpans of the citation nodes of the Passages of a Work
+// and all their containers, or nil if the Work has broken links or a Passage cites a range or subreference
+func citationSpans(work Work) map[string]leafSpan {
+	spans := map[string]leafSpan{}
+	add := func(node, id string) {
+		span, found := spans[node]
+		if !found {
+			span.first = id
+		}
+		span.last = id
+		spans[node] = span
+	}
+	it := NewIterator(work)
+	for it.Next() {
+		id := it.Passage().PassageID
+		urn := SplitCTS(id)
+		if urn.InValid || urn.Passage == "" || urn.Range || urn.Start.Subref.Exists {
+			return nil
+		}
+		for depth := 1; depth <= len(urn.Start.Citation); depth++ {
+			add(strings.Join(urn.Start.Citation[:depth], "."), id)
+		}
+	}
+	if it.Err() != nil {
+		return nil
+	}
+	return spans
+}
+
+// indexedBounds returns the slice indices of the first and last Passage the citation node of urn contains
+// as the citation index of the Work has them, along with a bool whether the index could be used.
+// Both must still be Passages of the Work contained by the node, the Passages before the first
+// and after the last must not be, as the Passages may have been changed by hand.
+func indexedBounds(urn CTSURN, work Work) (first, last int, found bool) {
+	span, found := work.citations.span(urn.Passage, work)
+	if !found {
+		return 0, 0, false
+	}
+	first, firstFound := GetIndexByID(span.first, work)
+	last, lastFound := GetIndexByID(span.last, work)
+	if !firstFound || !lastFound || !SplitCTS(span.first).IsContainedBy(urn) || !SplitCTS(span.last).IsContainedBy(urn) {
+		return 0, 0, false
+	}
+	before, after := work.Passages[first].Prev, work.Passages[last].Next
+	if before.Exists && validSlot(before.Index, work) && SplitCTS(work.Passages[before.Index].PassageID).IsContainedBy(urn) ||
+		after.Exists && validSlot(after.Index, work) && SplitCTS(work.Passages[after.Index].PassageID).IsContainedBy(urn) {
+		return 0, 0, false
+	}
+	return first, last, true
+}
+
+// leafBounds returns the PassageIDs of the first and last Passage the citation node of urn contains,
+// along with a bool whether it has found any
+func leafBounds(urn CTSURN, work Work) (first, last string, found bool) {
+	if first, last, found := indexedBounds(urn, work); found {
+		return work.Passages[first].PassageID, work.Passages[last].PassageID, true
+	}
+	leaves, err := GetLeafPassages(urn.ID, work)
+	if err != nil {
+		return "", "", false
+	}
+	return leaves[0].PassageID, leaves[len(leaves)-1].PassageID, true
+}
+
+// indexedLeaves returns the Passages contained by the citation node of urn, walking from
+// the first to the last one the citation index of the Work knows of, along with a bool
+// whether the index could be used
+func indexedLeaves(urn CTSURN, work Work) ([]Passage, bool) {
+	first, last, found := indexedBounds(urn, work)
+	if !found {
+		return nil, false
+	}
+	leaves := []Passage{}
+	it := &PassageIterator{work: work, cursor: PassLoc{Exists: true, PassageID: work.Passages[first].PassageID, Index: first},
+		stop: work.Passages[last].PassageID, visited: map[int]bool{}}
+	for it.Next() {
+		if p := it.Passage(); SplitCTS(p.PassageID).IsContainedBy(urn) {
+			leaves = append(leaves, p)
+		}
+	}
+	if it.Err() != nil || len(leaves) == 0 {
+		return nil, false
+	}
+	return leaves, true
+}
+
+// GetContainer returns the CTS URN of the citation node at the given depth that contains
+// the citation node of a CTS URN, e.g. urn:cts:greekLit:tlg0012.tlg001.msA:1 for the line
+// urn:cts:greekLit:tlg0012.tlg001.msA:1.45 at depth 1. Use CitationLevel to find the depth of a named level.
+func GetContainer(ctsID string, depth int, work Work) (string, error) {
+	urn := SplitCTS(ctsID).DropSubref()
+	if urn.InValid || urn.Passage == "" || urn.Range {
+		return "", errors.New("GetContainer: " + ctsID + " is not a citation node")
+	}
+	if depth < 1 || depth >= len(urn.Start.Citation) {
+		return "", errors.New("GetContainer: no container at depth " + strconv.Itoa(depth) + " for " + ctsID)
+	}
+	if _, err := passagesByID(urn.ID, work); err != nil {
+		return "", err
+	}
+	return cutToDepth(urn, depth), nil
+}
+
+// passagesByID returns the Passages a CTS URN refers to in document order, found by their links
+// rather than their text, all Passages of the Work for a URN without passage component,
+// which must name the Work at the level of its WorkID
+func passagesByID(ctsID string, work Work) ([]Passage, error) {
+	urn := SplitCTS(ctsID).DropSubref()
+	if urn.InValid {
+		return []Passage{}, errors.New("urn is not a valid cts urn")
+	}
+	if urn.Passage == "" {
+		if urn.DropPassage().ID != SplitCTS(work.WorkID).DropPassage().ID {
+			return []Passage{}, errors.New("urn " + ctsID + " does not name Work " + work.WorkID)
+		}
+		return documentOrder(work)
+	}
+	end := urn.Start
+	if urn.Range {
+		end = urn.End
+	}
+	startID, err := rangeBound(urn, urn.Start, true, work)
+	if err != nil {
+		return []Passage{}, err
+	}
+	endID, err := rangeBound(urn, end, false, work)
+	if err != nil {
+		return []Passage{}, err
+	}
+	return passagesBetween(startID, endID, work)
+}
+
+// cutToDepth returns the CTS URN of the citation node at depth containing the start of urn
+func cutToDepth(urn CTSURN, depth int) string {
+	if depth >= len(urn.Start.Citation) {
+		return urn.ID
+	}
+	return urn.withPassage(strings.Join(urn.Start.Citation[:depth], "."))
+}
@@ -0,0 +1,198 @@
+package gocite_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+var citationCEX = `#!ctscatalog
+urn#citationScheme#groupName#workTitle#versionLabel#exemplarLabel#online#lang
+urn:cts:greekLit:tlg0012.tlg001.msA:#book,line#Homeric epic#Iliad#HMT project diplomatic edition##true#grc
+
+#!ctsdata
+urn:cts:greekLit:tlg0012.tlg001.msA:1.1#Μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος
+urn:cts:greekLit:tlg0012.tlg001.msA:1.2#οὐλομένην, ἣ μυρί' Ἀχαιοῖς ἄλγε' ἔθηκε,
+urn:cts:greekLit:tlg0012.tlg001.msA:1.3#πολλὰς δ' ἰφθίμους ψυχὰς Ἄϊδι προΐαψεν
+urn:cts:greekLit:tlg0012.tlg001.msA:2.1#Ἄλλοι μέν ῥα θεοί τε καὶ ἀνέρες ἱπποκορυσταὶ
+urn:cts:greekLit:tlg0012.tlg001.msA:2.2#εὗδον παννύχιοι, Δία δ' οὐκ ἔχε νήδυμος ὕπνος,
+`
+
+const citationWork = "urn:cts:greekLit:tlg0012.tlg001.msA:"
+
+func testCitationWork(t *testing.T) gocite.Work {
+	data, err := gocite.ReadCEX(strings.NewReader(citationCEX))
+	if err != nil {
+		t.Fatal("Error calling ReadCEX: ", err)
+	}
+	return data.Textgroups[0].Works[0]
+}
+
+func TestCitationDepth(t *testing.T) {
+	work := testCitationWork(t)
+	if !reflect.DeepEqual(work.CitationScheme, []string{"book", "line"}) {
+		t.Error("expected citation scheme book,line, got", work.CitationScheme)
+	}
+	if depth := gocite.CitationDepth(work); depth != 2 {
+		t.Error("expected depth 2, got", depth)
+	}
+	if level, found := gocite.CitationLevel("book", work); !found || level != 1 {
+		t.Error("expected level 1 for book, got", level, found)
+	}
+	if _, found := gocite.CitationLevel("chapter", work); found {
+		t.Error("expected no level for chapter")
+	}
+	work.CitationScheme = nil
+	if depth := gocite.CitationDepth(work); depth != 2 {
+		t.Error("expected depth 2 from the passages, got", depth)
+	}
+}
+
+func TestGetValidReff(t *testing.T) {
+	work := testCitationWork(t)
+	tests := []struct {
+		input    string
+		depth    int
+		expected []string
+	}{
+		{input: citationWork, depth: 1, expected: []string{citationWork + "1", citationWork + "2"}},
+		{input: citationWork + "1", depth: 0, expected: []string{citationWork + "1.1", citationWork + "1.2", citationWork + "1.3"}},
+		{input: citationWork + "1.3-2.1", depth: 2, expected: []string{citationWork + "1.3", citationWork + "2.1"}},
+		{input: citationWork + "1.2-2", depth: 1, expected: []string{citationWork + "1", citationWork + "2"}},
+	}
+	for _, test := range tests {
+		reff, err := gocite.GetValidReff(test.input, test.depth, work)
+		if err != nil || !reflect.DeepEqual(reff, test.expected) {
+			t.Error("For", test.input, test.depth, "expected", test.expected, "got", reff, err)
+		}
+	}
+	if _, err := gocite.GetValidReff(citationWork, 3, work); err == nil {
+		t.Error("expected an error for depth 3")
+	}
+	if _, err := gocite.GetValidReff(citationWork+"3", 1, work); err == nil {
+		t.Error("expected an error for book 3")
+	}
+	for _, other := range []string{"urn:cts:latinLit:phi0959.phi006:", "urn:cts:greekLit:tlg0012.tlg001:"} {
+		if _, err := gocite.GetValidReff(other, 1, work); err == nil {
+			t.Error("expected an error for", other)
+		}
+	}
+}
+
+func TestGetLeafPassages(t *testing.T) {
+	work := testCitationWork(t)
+	leaves, err := gocite.GetLeafPassages(citationWork+"2", work)
+	if err != nil || len(leaves) != 2 || leaves[0].PassageID != citationWork+"2.1" || leaves[1].PassageID != citationWork+"2.2" {
+		t.Error("expected the lines of book 2, got", leaves, err)
+	}
+	if _, err := gocite.GetLeafPassages(citationWork+"1-2", work); err == nil {
+		t.Error("expected an error for a range")
+	}
+	unindexed := gocite.Work{WorkID: work.WorkID, Passages: work.Passages, First: work.First, Last: work.Last}
+	moved, err := gocite.MovePassage(citationWork+"2.1", "", work)
+	if err != nil {
+		t.Fatal("Error calling MovePassage: ", err)
+	}
+	for _, w := range []gocite.Work{unindexed, moved} {
+		leaves, err := gocite.GetLeafPassages(citationWork+"2", w)
+		if err != nil || len(leaves) != 2 || leaves[0].PassageID != citationWork+"2.1" || leaves[1].PassageID != citationWork+"2.2" {
+			t.Error("expected the lines of book 2, got", leaves, err)
+		}
+	}
+	if leaves, err := gocite.GetLeafPassages(citationWork+"1", moved); err != nil || len(leaves) != 3 || leaves[0].PassageID != citationWork+"1.1" {
+		t.Error("expected the lines of book 1 after the move, got", leaves, err)
+	}
+	text, err := gocite.ExtractTextByID(citationWork+"2", work)
+	if err != nil || len(text) != 2 || text[1].Text != "εὗδον παννύχιοι, Δία δ' οὐκ ἔχε νήδυμος ὕπνος," {
+		t.Error("expected the text of book 2, got", text, err)
+	}
+	text, err = gocite.ExtractTextByID(citationWork+"1-2.1", work)
+	if err != nil || len(text) != 4 || text[0].ID != citationWork+"1.1" || text[3].ID != citationWork+"2.1" {
+		t.Error("expected 1.1 to 2.1, got", text, err)
+	}
+}
+
+func TestGetLeafPassagesChangedByHand(t *testing.T) {
+	tests := []struct {
+		node     string
+		index    int
+		id       string
+		expected []string
+	}{
+		{node: "1", index: 2, id: "1.4", expected: []string{"1.1", "1.2", "1.4"}},
+		{node: "1", index: 3, id: "1.9", expected: []string{"1.1", "1.2", "1.3", "1.9"}},
+		{node: "1", index: 0, id: "0.1", expected: []string{"1.2", "1.3"}},
+		{node: "2", index: 2, id: "2.0", expected: []string{"2.0", "2.1", "2.2"}},
+	}
+	for _, test := range tests {
+		work := testCitationWork(t)
+		if _, err := gocite.GetLeafPassages(citationWork+test.node, work); err != nil {
+			t.Fatal("Error calling GetLeafPassages: ", err)
+		}
+		work.Passages[test.index].PassageID = citationWork + test.id
+		leaves, err := gocite.GetLeafPassages(citationWork+test.node, work)
+		ids := []string{}
+		for _, p := range leaves {
+			ids = append(ids, strings.TrimPrefix(p.PassageID, citationWork))
+		}
+		if err != nil || !reflect.DeepEqual(ids, test.expected) {
+			t.Error("For", test.id, "expected", test.expected, "got", ids, err)
+		}
+	}
+}
+
+func TestGetContainer(t *testing.T) {
+	work := testCitationWork(t)
+	tests := []struct {
+		input, expected string
+		depth           int
+		err             bool
+	}{
+		{input: citationWork + "1.3", depth: 1, expected: citationWork + "1"},
+		{input: citationWork + "2.1@θεοί", depth: 1, expected: citationWork + "2"},
+		{input: citationWork + "1.3", depth: 2, err: true},
+		{input: citationWork + "3.1", depth: 1, err: true},
+		{input: citationWork + "1.1-1.2", depth: 1, err: true},
+	}
+	for _, test := range tests {
+		container, err := gocite.GetContainer(test.input, test.depth, work)
+		if (err != nil) != test.err || container != test.expected {
+			t.Error("For", test.input, test.depth, "expected", test.expected, "got", container, err)
+		}
+	}
+}
+
+func TestExtractTextByIDAcrossLevels(t *testing.T) {
+	ordered := testCitationWork(t)
+	unordered := testCitationWork(t)
+	unordered.Ordered = false
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "1.3-2", expected: []string{"1.3", "2.1", "2.2"}},
+		{input: "1-2.1", expected: []string{"1.1", "1.2", "1.3", "2.1"}},
+		{input: "1-2", expected: []string{"1.1", "1.2", "1.3", "2.1", "2.2"}},
+		{input: "2-2", expected: []string{"2.1", "2.2"}},
+		{input: "1.2@οὐλομένην-2", expected: []string{"1.2@οὐλομένην", "1.3", "2.1", "2.2"}},
+	}
+	for _, work := range []gocite.Work{ordered, unordered} {
+		for _, test := range tests {
+			text, err := gocite.ExtractTextByID(citationWork+test.input, work)
+			ids := []string{}
+			for _, node := range text {
+				ids = append(ids, strings.TrimPrefix(node.ID, citationWork))
+			}
+			if err != nil || !reflect.DeepEqual(ids, test.expected) {
+				t.Error("For", test.input, "in ordered", work.Ordered, "work expected", test.expected, "got", ids, err)
+			}
+		}
+		for _, input := range []string{"2-1", "1-3", "2.2-1.1", "1.1@μῆνιν-3"} {
+			if _, err := gocite.ExtractTextByID(citationWork+input, work); err == nil {
+				t.Error("For", input, "in ordered", work.Ordered, "work expected an error")
+			}
+		}
+	}
+}
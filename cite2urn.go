@@ -0,0 +1,283 @@
+package gocite
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ParseCITEURN parses a CITE2 URN string into a Cite2Urn, enforcing the CITE2 URN grammar
+// urn:cite2:namespace:collection(.version(.property)?)?:object(@extension)?(-object(@extension)?)?
+// If the string is not a valid CITE2 URN, the returned error is a *URNError
+// and the returned Cite2Urn is marked as InValid.
+func ParseCITEURN(URNString string) (Cite2Urn, error) {
+	if err := validateCITE(URNString); err != nil {
+		return Cite2Urn{ID: URNString, InValid: true}, err
+	}
+	return parseCITE(URNString), nil
+}
+
+// validateCITE checks a CITE2 URN string component by component
+// and returns a *URNError for the first problem it finds
+func validateCITE(URNString string) error {
+	comps := strings.Split(URNString, ":")
+	if len(comps) < 5 {
+		return &URNError{URN: URNString, Component: "urn", Offset: len(URNString),
+			Message: fmt.Sprintf("expected 5 colon-separated components, found %d", len(comps))}
+	}
+	offsets := make([]int, len(comps))
+	for i := 1; i < len(comps); i++ {
+		offsets[i] = offsets[i-1] + len(comps[i-1]) + 1
+	}
+	if len(comps) > 5 {
+		return &URNError{URN: URNString, Component: "object", Offset: offsets[5] - 1, Message: "unexpected ':'"}
+	}
+	if comps[0] != "urn" {
+		return &URNError{URN: URNString, Component: "base", Offset: 0, Message: "expected \"urn\""}
+	}
+	if comps[1] != "cite2" {
+		return &URNError{URN: URNString, Component: "protocol", Offset: offsets[1], Message: "expected \"cite2\""}
+	}
+	if err := validateIdentifier(URNString, "namespace", comps[2], offsets[2], 1); err != nil {
+		return err
+	}
+	if err := validateIdentifier(URNString, "collection", comps[3], offsets[3], 3); err != nil {
+		return err
+	}
+	return validateObjectSelector(URNString, comps[4], offsets[4])
+}
+
+// validateObjectSelector checks the object component of a CITE2 URN:
+// an empty selector, a single object node or a range of two object nodes
+func validateObjectSelector(URNString, selector string, offset int) error {
+	if selector == "" {
+		return nil
+	}
+	hyphen := strings.Index(selector, "-")
+	if hyphen == -1 {
+		return validateObjectNode(URNString, selector, offset)
+	}
+	if second := strings.Index(selector[hyphen+1:], "-"); second != -1 {
+		return &URNError{URN: URNString, Component: "object", Offset: offset + hyphen + 1 + second,
+			Message: "more than one range hyphen"}
+	}
+	if err := validateObjectNode(URNString, selector[:hyphen], offset); err != nil {
+		return err
+	}
+	return validateObjectNode(URNString, selector[hyphen+1:], offset+hyphen+1)
+}
+
+// validateObjectNode checks a single object reference of the form object(@extension)?
+func validateObjectNode(URNString, node string, offset int) error {
+	if node == "" {
+		return &URNError{URN: URNString, Component: "object", Offset: offset, Message: "empty range end"}
+	}
+	object := node
+	at := strings.Index(node, "@")
+	if at != -1 {
+		object = node[:at]
+	}
+	if object == "" {
+		return &URNError{URN: URNString, Component: "object", Offset: offset, Message: "extension without object"}
+	}
+	for i, r := range object {
+		if r != '.' && !isPassageRune(r) {
+			return &URNError{URN: URNString, Component: "object", Offset: offset + i,
+				Message: fmt.Sprintf("invalid character %q", r)}
+		}
+	}
+	if at == -1 {
+		return nil
+	}
+	extension := node[at+1:]
+	if extension == "" {
+		return &URNError{URN: URNString, Component: "extension", Offset: offset + at + 1, Message: "must not be empty"}
+	}
+	for i, r := range extension {
+		if r == '@' || r == '#' || r == '?' || r == '/' || r == '\\' || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return &URNError{URN: URNString, Component: "extension", Offset: offset + at + 1 + i,
+				Message: fmt.Sprintf("invalid character %q", r)}
+		}
+	}
+	return nil
+}
+
+// parseCITE splits a CITE2 URN string with five colon-separated components
+// into all parts of a Cite2Urn. It does not check the components any further.
+func parseCITE(URNString string) Cite2Urn {
+	comps := strings.SplitN(URNString, ":", 5)
+	urn := Cite2Urn{ID: URNString,
+		Base:       comps[0],
+		Protocol:   comps[1],
+		Namespace:  comps[2],
+		Collection: comps[3],
+		Object:     comps[4]}
+	collParts := strings.SplitN(comps[3], ".", 3)
+	for i := len(collParts); i < 3; i++ {
+		collParts = append(collParts, "")
+	}
+	urn.CollectionID, urn.Version, urn.Property = collParts[0], collParts[1], collParts[2]
+	if urn.Object == "" {
+		return urn
+	}
+	rangeIndex := strings.Index(urn.Object, "-")
+	if rangeIndex == -1 {
+		urn.Start = parseObjectNode(urn.Object)
+		return urn
+	}
+	urn.Range = true
+	urn.Start = parseObjectNode(urn.Object[:rangeIndex])
+	urn.End = parseObjectNode(urn.Object[rangeIndex+1:])
+	return urn
+}
+
+// parseObjectNode splits a single object reference like "VA012RN_0013@0.1,0.2,0.3,0.4"
+// into the object identifier and its extension
+func parseObjectNode(nodeString string) ObjectNode {
+	node := ObjectNode{ID: nodeString, Object: nodeString}
+	if at := strings.Index(nodeString, "@"); at != -1 {
+		node.Object = nodeString[:at]
+		node.Extension = nodeString[at+1:]
+	}
+	return node
+}
+
+// String returns the CITE2 URN string of a Cite2Urn,
+// built from its components.
+func (urn Cite2Urn) String() string {
+	if urn.InValid {
+		return urn.ID
+	}
+	return strings.Join([]string{urn.Base, urn.Protocol, urn.Namespace, urn.CollectionComponent(), urn.objectString()}, ":")
+}
+
+// CollectionComponent returns the collection component of a Cite2Urn,
+// e.g. "vaimg.2017a" or "msA.v1.sequence"
+func (urn Cite2Urn) CollectionComponent() string {
+	parts := []string{urn.CollectionID, urn.Version, urn.Property}
+	for len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	return strings.Join(parts, ".")
+}
+
+// objectString joins the object nodes of a Cite2Urn, using a hyphen for ranges
+func (urn Cite2Urn) objectString() string {
+	if urn.Range {
+		return urn.Start.String() + "-" + urn.End.String()
+	}
+	return urn.Start.String()
+}
+
+// String returns the object reference of an ObjectNode, e.g. "VA012RN_0013@0.1,0.2,0.3,0.4"
+func (node ObjectNode) String() string {
+	if node.Extension != "" {
+		return node.Object + "@" + node.Extension
+	}
+	return node.Object
+}
+
+// DropObject returns a copy of urn without its object selector,
+// e.g. urn:cite2:hmt:msA.v1: for urn:cite2:hmt:msA.v1:12r
+func (urn Cite2Urn) DropObject() Cite2Urn {
+	if urn.InValid {
+		return urn
+	}
+	urn.Start, urn.End, urn.Range = ObjectNode{}, ObjectNode{}, false
+	return urn.refresh()
+}
+
+// DropVersion returns a copy of urn reduced to the notional collection level,
+// dropping both version and property
+func (urn Cite2Urn) DropVersion() Cite2Urn {
+	if urn.InValid {
+		return urn
+	}
+	urn.Version, urn.Property = "", ""
+	return urn.refresh()
+}
+
+// DropProperty returns a copy of urn without its property
+func (urn Cite2Urn) DropProperty() Cite2Urn {
+	if urn.InValid {
+		return urn
+	}
+	urn.Property = ""
+	return urn.refresh()
+}
+
+// DropExtension returns a copy of urn without extensions on its object nodes
+func (urn Cite2Urn) DropExtension() Cite2Urn {
+	if urn.InValid {
+		return urn
+	}
+	urn.Start.Extension, urn.End.Extension = "", ""
+	urn.Start.ID, urn.End.ID = urn.Start.String(), urn.End.String()
+	return urn.refresh()
+}
+
+// refresh recomputes the ID, Collection and Object strings of urn from its parts
+func (urn Cite2Urn) refresh() Cite2Urn {
+	urn.Collection = urn.CollectionComponent()
+	urn.Object = urn.objectString()
+	urn.ID = urn.String()
+	return urn
+}
+
+// IsContainedBy tests whether urn refers to the same or a part of what other refers to.
+// Version and property left empty in other match any value in urn,
+// an empty object selector in other matches every object of the collection,
+// and an object with an extension is contained by the same object without one.
+// As a URN carries no information about collection order, a range only contains
+// its own end points and what is contained by them.
+func (urn Cite2Urn) IsContainedBy(other Cite2Urn) bool {
+	if urn.InValid || other.InValid {
+		return false
+	}
+	if urn.Namespace != other.Namespace || urn.CollectionID != other.CollectionID {
+		return false
+	}
+	if (other.Version != "" && urn.Version != other.Version) || (other.Property != "" && urn.Property != other.Property) {
+		return false
+	}
+	switch {
+	case other.Object == "":
+		return true
+	case urn.Object == "":
+		return false
+	case other.Range && urn.Range:
+		return urn.Start.String() == other.Start.String() && urn.End.String() == other.End.String()
+	case other.Range:
+		return urn.Start.IsContainedBy(other.Start) || urn.Start.IsContainedBy(other.End)
+	case urn.Range:
+		return urn.Start.IsContainedBy(other.Start) && urn.End.IsContainedBy(other.Start)
+	default:
+		return urn.Start.IsContainedBy(other.Start)
+	}
+}
+
+// Contains tests whether other refers to the same or a part of what urn refers to.
+// See IsContainedBy.
+func (urn Cite2Urn) Contains(other Cite2Urn) bool {
+	return other.IsContainedBy(urn)
+}
+
+// UrnMatch tests whether either of two Cite2Urns contains the other,
+// following the "twiddle" (~~) comparison of the CITE architecture
+func (urn Cite2Urn) UrnMatch(other Cite2Urn) bool {
+	return urn.IsContainedBy(other) || other.IsContainedBy(urn)
+}
+
+// MatchIgnoringVersion tests whether two Cite2Urns match at the notional collection level,
+// ignoring version and property
+func (urn Cite2Urn) MatchIgnoringVersion(other Cite2Urn) bool {
+	return urn.DropVersion().UrnMatch(other.DropVersion())
+}
+
+// IsContainedBy tests whether node refers to the same or a part of the object other refers to
+func (node ObjectNode) IsContainedBy(other ObjectNode) bool {
+	if node.Object != other.Object {
+		return false
+	}
+	return other.Extension == "" || node.Extension == other.Extension
+}
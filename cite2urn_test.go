@@ -0,0 +1,120 @@
+package gocite_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+type parseCITETestpair struct {
+	input     string
+	output    gocite.Cite2Urn
+	component string
+	offset    int
+}
+
+var parseCITETests = []parseCITETestpair{
+	{input: "urn:cite2:hmt:vaimg.2017a:VA012RN_0013@0.1,0.2,0.3,0.4",
+		output: gocite.Cite2Urn{ID: "urn:cite2:hmt:vaimg.2017a:VA012RN_0013@0.1,0.2,0.3,0.4", Base: "urn", Protocol: "cite2", Namespace: "hmt",
+			Collection: "vaimg.2017a", Object: "VA012RN_0013@0.1,0.2,0.3,0.4", CollectionID: "vaimg", Version: "2017a",
+			Start: gocite.ObjectNode{ID: "VA012RN_0013@0.1,0.2,0.3,0.4", Object: "VA012RN_0013", Extension: "0.1,0.2,0.3,0.4"}}},
+	{input: "urn:cite2:hmt:msA.v1.sequence:1r-12v",
+		output: gocite.Cite2Urn{ID: "urn:cite2:hmt:msA.v1.sequence:1r-12v", Base: "urn", Protocol: "cite2", Namespace: "hmt",
+			Collection: "msA.v1.sequence", Object: "1r-12v", CollectionID: "msA", Version: "v1", Property: "sequence",
+			Start: gocite.ObjectNode{ID: "1r", Object: "1r"},
+			End:   gocite.ObjectNode{ID: "12v", Object: "12v"},
+			Range: true}},
+	{input: "urn:cite2:hmt:msA:",
+		output: gocite.Cite2Urn{ID: "urn:cite2:hmt:msA:", Base: "urn", Protocol: "cite2", Namespace: "hmt", Collection: "msA", CollectionID: "msA"}},
+	{input: "urn:cite2:hmt:msA.v1.sequence.x:1r", component: "collection", offset: 29},
+	{input: "urn:cite2:hmt::1r", component: "collection", offset: 14},
+	{input: "urn:cts:hmt:msA.v1:1r", component: "protocol", offset: 4},
+	{input: "urn:cite2:hmt:msA.v1:1r-2r-3r", component: "object", offset: 26},
+	{input: "urn:cite2:hmt:msA.v1:@0.1", component: "object", offset: 21},
+	{input: "urn:cite2:hmt:msA.v1:1r@", component: "extension", offset: 24},
+	{input: "urn:cite2:hmt:msA.v1:1r@0.1@0.2", component: "extension", offset: 27},
+}
+
+func TestParseCITEURN(t *testing.T) {
+	for _, pair := range parseCITETests {
+		urn, err := gocite.ParseCITEURN(pair.input)
+		if pair.component == "" {
+			if err != nil {
+				t.Error("For", pair.input, "expected no error, got", err)
+				continue
+			}
+			if !reflect.DeepEqual(urn, pair.output) {
+				t.Error(
+					"For", pair.input,
+					"expected", pair.output,
+					"got", urn,
+				)
+			}
+			if urn.String() != pair.input {
+				t.Error("For", pair.input, "expected String() to round-trip, got", urn.String())
+			}
+			continue
+		}
+		urnErr, ok := err.(*gocite.URNError)
+		if !ok {
+			t.Error("For", pair.input, "expected *URNError, got", err)
+			continue
+		}
+		if urnErr.Component != pair.component || urnErr.Offset != pair.offset {
+			t.Error(
+				"For", pair.input,
+				"expected", pair.component, pair.offset,
+				"got", urnErr.Component, urnErr.Offset, urnErr.Message,
+			)
+		}
+		if !urn.InValid || gocite.IsCITEURN(pair.input) {
+			t.Error("For", pair.input, "expected urn to be invalid")
+		}
+	}
+}
+
+func TestCite2UrnContainment(t *testing.T) {
+	tests := []struct {
+		urn, other            string
+		containedBy, urnMatch bool
+		ignoringVersion       bool
+	}{
+		{urn: "urn:cite2:hmt:vaimg.2017a:VA012RN_0013@0.1,0.2,0.3,0.4", other: "urn:cite2:hmt:vaimg.2017a:VA012RN_0013",
+			containedBy: true, urnMatch: true, ignoringVersion: true},
+		{urn: "urn:cite2:hmt:vaimg.2017a:VA012RN_0013", other: "urn:cite2:hmt:vaimg:",
+			containedBy: true, urnMatch: true, ignoringVersion: true},
+		{urn: "urn:cite2:hmt:msA.v1.sequence:12r", other: "urn:cite2:hmt:msA.v1:12r",
+			containedBy: true, urnMatch: true, ignoringVersion: true},
+		{urn: "urn:cite2:hmt:msA.v1:12r", other: "urn:cite2:hmt:msA.v2:12r",
+			containedBy: false, urnMatch: false, ignoringVersion: true},
+		{urn: "urn:cite2:hmt:msA.v1:12r", other: "urn:cite2:hmt:msA.v1:12v",
+			containedBy: false, urnMatch: false, ignoringVersion: false},
+		{urn: "urn:cite2:hmt:msA.v1:12r", other: "urn:cite2:hmt:msA.v1:12r-14v",
+			containedBy: true, urnMatch: true, ignoringVersion: true},
+	}
+	for _, pair := range tests {
+		urn, other := gocite.SplitCITE(pair.urn), gocite.SplitCITE(pair.other)
+		results := []bool{urn.IsContainedBy(other), other.Contains(urn), urn.UrnMatch(other), other.UrnMatch(urn), urn.MatchIgnoringVersion(other)}
+		expected := []bool{pair.containedBy, pair.containedBy, pair.urnMatch, pair.urnMatch, pair.ignoringVersion}
+		if !reflect.DeepEqual(results, expected) {
+			t.Error(
+				"For", pair.urn, "and", pair.other,
+				"expected", expected,
+				"got", results,
+			)
+		}
+	}
+	urn := gocite.SplitCITE("urn:cite2:hmt:msA.v1.sequence:12r@0.1,0.2,0.3,0.4")
+	drops := map[string]gocite.Cite2Urn{
+		"urn:cite2:hmt:msA.v1.sequence:":           urn.DropObject(),
+		"urn:cite2:hmt:msA:12r@0.1,0.2,0.3,0.4":    urn.DropVersion(),
+		"urn:cite2:hmt:msA.v1:12r@0.1,0.2,0.3,0.4": urn.DropProperty(),
+		"urn:cite2:hmt:msA.v1.sequence:12r":        urn.DropExtension(),
+	}
+	for expected, output := range drops {
+		if !reflect.DeepEqual(output, gocite.SplitCITE(expected)) {
+			t.Error("expected", gocite.SplitCITE(expected), "got", output)
+		}
+	}
+}
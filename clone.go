@@ -0,0 +1,61 @@
+package gocite
+
+// Clone returns a deep copy of a Work that shares no slices with the original, only its indices,
+// which are never changed. It is only needed to change Passages by hand: the functions of this package
+// that edit a Work, like InsertPassage, DelPassage, InsertPassagesAfter or MovePassage, never change
+// the Work they are given. They return a new Work with a Passages slice of its own, in which only the
+// Passages the edit touches differ, while the Analysis and ImageLinks of the Passages are shared
+// with the original and its ID index is extended rather than copied, so that earlier versions stay valid.
+// The Passages slice itself, though, is copied on every edit, see the package documentation.
+func Clone(work Work) Work {
+	clone := work
+	if work.Passages != nil {
+		clone.Passages = make([]Passage, len(work.Passages))
+		for i := range work.Passages {
+			clone.Passages[i] = ClonePassage(work.Passages[i])
+		}
+	}
+	if work.CitationScheme != nil {
+		clone.CitationScheme = append([]string{}, work.CitationScheme...)
+	}
+	return clone
+}
+
+// ClonePassage returns a deep copy of a Passage that shares no slices with the original
+func ClonePassage(p Passage) Passage {
+	clone := p
+	if p.Analysis != nil {
+		clone.Analysis = make([]Tokenisation, len(p.Analysis))
+		for i, t := range p.Analysis {
+			if t.Array.CharRepres != nil {
+				t.Array.CharRepres = append([]string{}, t.Array.CharRepres...)
+			}
+			if t.Array.IntRepres != nil {
+				t.Array.IntRepres = append([]int{}, t.Array.IntRepres...)
+			}
+			if t.Array.BoolRepres != nil {
+				t.Array.BoolRepres = append([]bool{}, t.Array.BoolRepres...)
+			}
+			if t.Array.FloatRepres != nil {
+				t.Array.FloatRepres = append([]float64{}, t.Array.FloatRepres...)
+			}
+			clone.Analysis[i] = t
+		}
+	}
+	if p.ImageLinks != nil {
+		clone.ImageLinks = append([]Triple{}, p.ImageLinks...)
+	}
+	return clone
+}
+
+// ownPassages returns the Work with a copy of its Passages slice with room for one more Passage,
+// so that Passages can be changed and added without touching the Work it was given.
+// The ID index is shared, see passageIndex.
+func ownPassages(work Work) Work {
+	covered := work.idIndex.covers(work.Passages)
+	work.Passages = append(make([]Passage, 0, len(work.Passages)+1), work.Passages...)
+	if covered {
+		work.idIndex = work.idIndex.over(work.Passages)
+	}
+	return work
+}
@@ -0,0 +1,100 @@
+package gocite_test
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+func TestEditsKeepOriginal(t *testing.T) {
+	original := testCitationWork(t)
+	before := gocite.Clone(original)
+	inserted, err := gocite.InsertPassage(gocite.Passage{PassageID: citationWork + "1.4", Analysis: txtAnalysis("new"),
+		Prev: gocite.PassLoc{PassageID: citationWork + "1.3"}, Next: gocite.PassLoc{PassageID: citationWork + "2.1"}}, original)
+	if err != nil {
+		t.Fatal("Error calling InsertPassage: ", err)
+	}
+	deleted, err := gocite.DelPassage(citationWork+"1.2", inserted)
+	if err != nil {
+		t.Fatal("Error calling DelPassage: ", err)
+	}
+	moved, err := gocite.MovePassage(citationWork+"2.2", "", deleted)
+	if err != nil {
+		t.Fatal("Error calling MovePassage: ", err)
+	}
+	if !reflect.DeepEqual(original, before) {
+		t.Error("expected the original Work to be unchanged, got", original.Passages)
+	}
+	versions := []struct {
+		work     gocite.Work
+		expected []string
+	}{
+		{work: original, expected: []string{"1.1", "1.2", "1.3", "2.1", "2.2"}},
+		{work: inserted, expected: []string{"1.1", "1.2", "1.3", "1.4", "2.1", "2.2"}},
+		{work: deleted, expected: []string{"1.1", "1.3", "1.4", "2.1", "2.2"}},
+		{work: moved, expected: []string{"2.2", "1.1", "1.3", "1.4", "2.1"}},
+	}
+	for i, version := range versions {
+		if ids := documentIDs(t, version.work); !reflect.DeepEqual(ids, version.expected) {
+			t.Error("For version", i, "expected", version.expected, "got", ids)
+		}
+		if _, found := gocite.GetIndexByID(citationWork+"1.4", version.work); found != (i > 0) {
+			t.Error("For version", i, "unexpected lookup of 1.4")
+		}
+	}
+}
+
+func TestEditHistory(t *testing.T) {
+	versions := []gocite.Work{testCitationWork(t)}
+	expected := [][]string{{"1.1", "1.2", "1.3", "2.1", "2.2"}}
+	for i := 1; i <= 40; i++ {
+		work, ids := versions[len(versions)-1], expected[len(expected)-1]
+		var err error
+		if i%4 == 0 {
+			work, err = gocite.DelPassage(citationWork+ids[len(ids)-2], work)
+			ids = append(append([]string{}, ids[:len(ids)-2]...), ids[len(ids)-1])
+		} else {
+			id := "3." + strconv.Itoa(i)
+			work, err = gocite.InsertPassage(gocite.Passage{PassageID: citationWork + id, Prev: work.Last}, work)
+			ids = append(append([]string{}, ids...), id)
+		}
+		if err != nil {
+			t.Fatal("Error editing version", i, err)
+		}
+		versions, expected = append(versions, work), append(expected, ids)
+	}
+	for i, work := range versions {
+		if ids := documentIDs(t, work); !reflect.DeepEqual(ids, expected[i]) {
+			t.Error("For version", i, "expected", expected[i], "got", ids)
+		}
+		present := map[string]bool{}
+		for _, id := range expected[i] {
+			present[id] = true
+		}
+		for _, id := range append([]string{"1.2", "3.2", "3.3"}, expected[len(expected)-1]...) {
+			index, found := gocite.GetIndexByID(citationWork+id, work)
+			if found != present[id] || found && work.Passages[index].PassageID != citationWork+id {
+				t.Error("For version", i, "unexpected lookup of", id, index, found)
+			}
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	work := testCitationWork(t)
+	clone := gocite.Clone(work)
+	if !reflect.DeepEqual(clone, work) {
+		t.Fatal("expected the clone to equal the original")
+	}
+	clone.Passages[0].Analysis[0].Array.CharRepres[0] = "x"
+	clone.Passages[1].PassageID = "changed"
+	clone.CitationScheme[0] = "chapter"
+	if text, _ := gocite.PassageText(work.Passages[0]); text != "Μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος" {
+		t.Error("expected the text of the original to be unchanged, got", text)
+	}
+	if work.Passages[1].PassageID != citationWork+"1.2" || work.CitationScheme[0] != "book" {
+		t.Error("expected the original to be unchanged")
+	}
+}
@@ -0,0 +1,363 @@
+// Command gocite validates CTS and CITE2 URNs and queries, converts,
+// sorts and summarises corpora stored as CEX or JSON.
+//
+// Usage:
+//
+//	gocite validate <urn>
+//	gocite text <file> <urn>
+//	gocite reff <file> <urn> [-level n]
+//	gocite convert <file> [-to cex|json] [-delimiter d] [-subdelimiter d]
+//	gocite sort <file>
+//	gocite stats <file>
+//
+// Files ending in .json are read as JSON encoded CEXData, all other files as CEX.
+// Every command accepts -json to write its result as JSON instead of plain text.
+//
+// Exit codes:
+//
+//	0 success
+//	1 the URN or the corpus is invalid
+//	2 wrong usage
+//	3 the URN does not refer to anything in the corpus
+//	4 a file could not be read or written
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ThomasK81/gocite"
+)
+
+// Exit codes of the gocite command
+const (
+	exitOK       = 0
+	exitInvalid  = 1
+	exitUsage    = 2
+	exitNotFound = 3
+	exitIO       = 4
+)
+
+const usage = `usage: gocite <command> [arguments]
+
+commands:
+  validate <urn>                     check whether a CTS or CITE2 URN is valid
+  text <file> <urn>                  print the text a CTS URN refers to
+  reff <file> <urn> [-level n]       print the URNs of the citation nodes of a CTS URN
+  convert <file> [-to cex|json]      convert a corpus between CEX and JSON
+  sort <file>                        write a corpus with all works in document order
+  stats <file>                       print the number of works, passages and objects
+
+Every command accepts -json to print its result as JSON.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command given in args and returns the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+	cmd := command{name: args[0], stdout: stdout, stderr: stderr}
+	cmd.flags = flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	cmd.flags.SetOutput(stderr)
+	cmd.flags.BoolVar(&cmd.json, "json", false, "write the result as JSON")
+	switch cmd.name {
+	case "validate":
+		return cmd.validate(args[1:])
+	case "text":
+		return cmd.text(args[1:])
+	case "reff":
+		return cmd.reff(args[1:])
+	case "convert":
+		return cmd.convert(args[1:])
+	case "sort":
+		return cmd.sort(args[1:])
+	case "stats":
+		return cmd.stats(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "gocite: unknown command %q\n%s", cmd.name, usage)
+		return exitUsage
+	}
+}
+
+// command holds the flags and output of a single invocation
+type command struct {
+	name           string
+	flags          *flag.FlagSet
+	json           bool
+	stdout, stderr io.Writer
+}
+
+// parse parses flags anywhere among the arguments and returns the positional arguments,
+// which must number exactly n
+func (cmd *command) parse(args []string, n int) ([]string, bool) {
+	positional := []string{}
+	for {
+		if err := cmd.flags.Parse(args); err != nil {
+			return nil, false
+		}
+		if cmd.flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, cmd.flags.Arg(0))
+		args = cmd.flags.Args()[1:]
+	}
+	if len(positional) != n {
+		fmt.Fprintf(cmd.stderr, "gocite %s: expected %d arguments, got %d\n", cmd.name, n, len(positional))
+		return nil, false
+	}
+	return positional, true
+}
+
+// fail reports err and returns code
+func (cmd *command) fail(code int, err error) int {
+	fmt.Fprintf(cmd.stderr, "gocite %s: %v\n", cmd.name, err)
+	return code
+}
+
+// print writes v as JSON in JSON mode, or else the lines of plain
+func (cmd *command) print(v interface{}, plain []string) int {
+	if cmd.json {
+		enc := json.NewEncoder(cmd.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(v); err != nil {
+			return cmd.fail(exitIO, err)
+		}
+		return exitOK
+	}
+	for _, line := range plain {
+		if _, err := fmt.Fprintln(cmd.stdout, line); err != nil {
+			return cmd.fail(exitIO, err)
+		}
+	}
+	return exitOK
+}
+
+// validate checks a CTS or CITE2 URN
+func (cmd *command) validate(args []string) int {
+	positional, ok := cmd.parse(args, 1)
+	if !ok {
+		return exitUsage
+	}
+	var err error
+	if strings.HasPrefix(positional[0], "urn:cite2:") {
+		_, err = gocite.ParseCITEURN(positional[0])
+	} else {
+		_, err = gocite.ParseCTSURN(positional[0])
+	}
+	result := struct {
+		URN   string `json:"urn"`
+		Valid bool   `json:"valid"`
+		Error string `json:"error,omitempty"`
+	}{URN: positional[0], Valid: err == nil}
+	line := "valid"
+	if err != nil {
+		result.Error = err.Error()
+		line = err.Error()
+	}
+	if code := cmd.print(result, []string{line}); code != exitOK {
+		return code
+	}
+	if err != nil {
+		return exitInvalid
+	}
+	return exitOK
+}
+
+// text prints the text a CTS URN refers to, see ExtractTextByID
+func (cmd *command) text(args []string) int {
+	positional, ok := cmd.parse(args, 2)
+	if !ok {
+		return exitUsage
+	}
+	data, code := cmd.read(positional[0])
+	if code != exitOK {
+		return code
+	}
+	if _, err := gocite.ParseCTSURN(positional[1]); err != nil {
+		return cmd.fail(exitInvalid, err)
+	}
+	texts, err := gocite.NewTextRepository(data).ExtractTextByID(positional[1])
+	if err != nil {
+		return cmd.fail(exitNotFound, err)
+	}
+	type node struct {
+		URN  string `json:"urn"`
+		Text string `json:"text"`
+	}
+	nodes := []node{}
+	plain := []string{}
+	for _, text := range texts {
+		nodes = append(nodes, node{URN: text.ID, Text: text.Text})
+		plain = append(plain, text.ID+"\t"+text.Text)
+	}
+	return cmd.print(nodes, plain)
+}
+
+// reff prints the URNs of the citation nodes a CTS URN refers to.
+// With -level, the URNs of the citation nodes at that level are printed, e.g. books instead of lines.
+func (cmd *command) reff(args []string) int {
+	level := cmd.flags.Int("level", 0, "citation level of the URNs, 0 for the passages themselves")
+	positional, ok := cmd.parse(args, 2)
+	if !ok {
+		return exitUsage
+	}
+	if *level < 0 {
+		return cmd.fail(exitUsage, fmt.Errorf("invalid level %d", *level))
+	}
+	data, code := cmd.read(positional[0])
+	if code != exitOK {
+		return code
+	}
+	if _, err := gocite.ParseCTSURN(positional[1]); err != nil {
+		return cmd.fail(exitInvalid, err)
+	}
+	reff, err := gocite.NewTextRepository(data).GetValidReff(positional[1], *level)
+	if err != nil {
+		return cmd.fail(exitNotFound, err)
+	}
+	return cmd.print(reff, reff)
+}
+
+// convert writes a corpus as CEX or JSON
+func (cmd *command) convert(args []string) int {
+	to := cmd.flags.String("to", "cex", "output format, cex or json")
+	opts := gocite.CEXOptions{}
+	cmd.flags.StringVar(&opts.Delimiter, "delimiter", "#", "column delimiter of CEX output")
+	cmd.flags.StringVar(&opts.SubDelimiter, "subdelimiter", ",", "list delimiter of CEX output")
+	positional, ok := cmd.parse(args, 1)
+	if !ok {
+		return exitUsage
+	}
+	if *to != "cex" && *to != "json" {
+		return cmd.fail(exitUsage, fmt.Errorf("unknown format %q", *to))
+	}
+	data, code := cmd.read(positional[0])
+	if code != exitOK {
+		return code
+	}
+	return cmd.write(data, *to == "json", opts)
+}
+
+// sort writes a corpus as CEX with the Passages of all Works sorted into document order
+func (cmd *command) sort(args []string) int {
+	positional, ok := cmd.parse(args, 1)
+	if !ok {
+		return exitUsage
+	}
+	data, code := cmd.read(positional[0])
+	if code != exitOK {
+		return code
+	}
+	for i := range data.Textgroups {
+		group := &data.Textgroups[i]
+		works := make([]gocite.Work, len(group.Works))
+		for j, work := range group.Works {
+			sorted, err := gocite.SortPassages(work)
+			if err != nil {
+				return cmd.fail(exitInvalid, fmt.Errorf("%s: %v", work.WorkID, err))
+			}
+			works[j] = sorted
+		}
+		group.Works = works
+	}
+	return cmd.write(data, cmd.json, gocite.CEXOptions{})
+}
+
+// stats prints the number of textgroups, works, passages, collections, objects and relations
+func (cmd *command) stats(args []string) int {
+	positional, ok := cmd.parse(args, 1)
+	if !ok {
+		return exitUsage
+	}
+	data, code := cmd.read(positional[0])
+	if code != exitOK {
+		return code
+	}
+	type workStats struct {
+		URN      string `json:"urn"`
+		Passages int    `json:"passages"`
+	}
+	stats := struct {
+		Textgroups  int         `json:"textgroups"`
+		Works       []workStats `json:"works"`
+		Passages    int         `json:"passages"`
+		Collections int         `json:"collections"`
+		Objects     int         `json:"objects"`
+		Relations   int         `json:"relations"`
+	}{Textgroups: len(data.Textgroups), Works: []workStats{}, Collections: len(data.Collections), Relations: len(data.Relations)}
+	for _, group := range data.Textgroups {
+		for _, work := range group.Works {
+			stats.Works = append(stats.Works, workStats{URN: work.WorkID, Passages: len(work.Passages)})
+			stats.Passages += len(work.Passages)
+		}
+	}
+	for _, coll := range data.Collections {
+		stats.Objects += len(coll.Objects)
+	}
+	plain := []string{
+		fmt.Sprintf("textgroups\t%d", stats.Textgroups),
+		fmt.Sprintf("works\t%d", len(stats.Works)),
+		fmt.Sprintf("passages\t%d", stats.Passages),
+		fmt.Sprintf("collections\t%d", stats.Collections),
+		fmt.Sprintf("objects\t%d", stats.Objects),
+		fmt.Sprintf("relations\t%d", stats.Relations),
+	}
+	for _, work := range stats.Works {
+		plain = append(plain, fmt.Sprintf("%s\t%d", work.URN, work.Passages))
+	}
+	return cmd.print(stats, plain)
+}
+
+// read reads a corpus from a CEX or JSON file
+func (cmd *command) read(path string) (gocite.CEXData, int) {
+	f, err := os.Open(path)
+	if err != nil {
+		return gocite.CEXData{}, cmd.fail(exitIO, err)
+	}
+	defer f.Close()
+	if !strings.HasSuffix(strings.ToLower(path), ".json") {
+		data, err := gocite.ReadCEX(f)
+		if err != nil {
+			return data, cmd.fail(exitInvalid, err)
+		}
+		return data, exitOK
+	}
+	var data gocite.CEXData
+	if err := json.NewDecoder(f).Decode(&data); err != nil {
+		return data, cmd.fail(exitInvalid, err)
+	}
+	for i := range data.Textgroups {
+		for j := range data.Textgroups[i].Works {
+			data.Textgroups[i].Works[j] = gocite.BuildIDIndex(data.Textgroups[i].Works[j])
+		}
+	}
+	return data, exitOK
+}
+
+// write writes a corpus to stdout as JSON or CEX
+func (cmd *command) write(data gocite.CEXData, asJSON bool, opts gocite.CEXOptions) int {
+	if asJSON {
+		enc := json.NewEncoder(cmd.stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(data); err != nil {
+			return cmd.fail(exitIO, err)
+		}
+		return exitOK
+	}
+	if err := gocite.WriteCEX(cmd.stdout, data, opts); err != nil {
+		return cmd.fail(exitInvalid, err)
+	}
+	return exitOK
+}
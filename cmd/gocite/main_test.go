@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testCEX = `#!ctscatalog
+urn#citationScheme#groupName#workTitle#versionLabel#exemplarLabel#online#lang
+urn:cts:greekLit:tlg0012.tlg001.msA:#book,line#Homeric epic#Iliad#HMT project diplomatic edition##true#grc
+
+#!ctsdata
+urn:cts:greekLit:tlg0012.tlg001.msA:1.1#Μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος
+urn:cts:greekLit:tlg0012.tlg001.msA:1.2#οὐλομένην, ἣ μυρί' Ἀχαιοῖς ἄλγε' ἔθηκε,
+urn:cts:greekLit:tlg0012.tlg001.msA:2.1#Ἄλλοι μέν ῥα θεοί τε καὶ ἀνέρες ἱπποκορυσταὶ
+`
+
+func testFile(t *testing.T, name, content string) string {
+	dir, err := os.MkdirTemp("", "gocite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	cex := testFile(t, "iliad.cex", testCEX)
+	work := "urn:cts:greekLit:tlg0012.tlg001.msA:"
+	tests := []struct {
+		args   []string
+		code   int
+		output string
+	}{
+		{args: []string{"validate", work + "1.1"}, code: exitOK, output: "valid\n"},
+		{args: []string{"validate", "urn:cite2:hmt:msA.v1:12r"}, code: exitOK, output: "valid\n"},
+		{args: []string{"validate", "urn:cts:greekLit:tlg0012.tlg001.msA"}, code: exitInvalid},
+		{args: []string{"text", cex, work + "1.2"}, code: exitOK, output: work + "1.2\tοὐλομένην, ἣ μυρί' Ἀχαιοῖς ἄλγε' ἔθηκε,\n"},
+		{args: []string{"text", "-json", cex, work + "1.1@ἄειδε"}, code: exitOK,
+			output: "[\n  {\n    \"urn\": \"" + work + "1.1@ἄειδε\",\n    \"text\": \"ἄειδε\"\n  }\n]\n"},
+		{args: []string{"text", cex, work + "3.1"}, code: exitNotFound},
+		{args: []string{"text", cex, "urn:cts:greekLit"}, code: exitInvalid},
+		{args: []string{"reff", cex, "urn:cts:greekLit:tlg0012.tlg001:", "-level", "1"}, code: exitOK, output: work + "1\n" + work + "2\n"},
+		{args: []string{"reff", cex, work + "1"}, code: exitOK, output: work + "1.1\n" + work + "1.2\n"},
+		{args: []string{"text", cex}, code: exitUsage},
+		{args: []string{"convert", "-to", "xml", cex}, code: exitUsage},
+		{args: []string{"stats", filepath.Join(filepath.Dir(cex), "missing.cex")}, code: exitIO},
+		{args: []string{"frobnicate"}, code: exitUsage},
+		{args: []string{}, code: exitUsage},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(test.args, &stdout, &stderr)
+		if code != test.code {
+			t.Error("For", test.args, "expected exit code", test.code, "got", code, stderr.String())
+		}
+		if test.output != "" && stdout.String() != test.output {
+			t.Errorf("For %v expected %q, got %q", test.args, test.output, stdout.String())
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	cex := testFile(t, "iliad.cex", testCEX)
+	var asJSON, stderr bytes.Buffer
+	if code := run([]string{"convert", "-to", "json", cex}, &asJSON, &stderr); code != exitOK {
+		t.Fatal("convert to json failed:", stderr.String())
+	}
+	jsonFile := testFile(t, "iliad.json", asJSON.String())
+	var asCEX bytes.Buffer
+	if code := run([]string{"convert", jsonFile, "-delimiter", "|"}, &asCEX, &stderr); code != exitOK {
+		t.Fatal("convert to cex failed:", stderr.String())
+	}
+	if !strings.Contains(asCEX.String(), "urn:cts:greekLit:tlg0012.tlg001.msA:2.1|Ἄλλοι μέν ῥα θεοί τε καὶ ἀνέρες ἱπποκορυσταὶ\n") {
+		t.Error("unexpected CEX", asCEX.String())
+	}
+	var sorted bytes.Buffer
+	if code := run([]string{"sort", jsonFile}, &sorted, &stderr); code != exitOK {
+		t.Fatal("sort failed:", stderr.String())
+	}
+	var stats bytes.Buffer
+	if code := run([]string{"stats", "-json", jsonFile}, &stats, &stderr); code != exitOK {
+		t.Fatal("stats failed:", stderr.String())
+	}
+	var result struct {
+		Textgroups int `json:"textgroups"`
+		Passages   int `json:"passages"`
+	}
+	if err := json.Unmarshal(stats.Bytes(), &result); err != nil || result.Textgroups != 1 || result.Passages != 3 {
+		t.Error("unexpected stats", stats.String(), err)
+	}
+}
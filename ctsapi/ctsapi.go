@@ -0,0 +1,449 @@
+// Package ctsapi serves the texts of a gocite.TextRepository
+// following the CTS API, answering GetCapabilities, GetValidReff, GetPassage,
+// GetPassagePlus, GetPrevNextUrn, GetFirstUrn and GetLabel requests with CTS XML.
+package ctsapi
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ThomasK81/gocite"
+)
+
+// CTS error codes as defined by the CTS API
+const (
+	InvalidRequestName  = 1
+	InvalidURNSyntax    = 2
+	InvalidURNReference = 3
+	InvalidLevel        = 4
+)
+
+const ctsNS = "http://chs.harvard.edu/xmlns/cts"
+
+// Handler is an http.Handler answering CTS API requests for the texts of a TextRepository.
+// The request is given in the query parameter "request", e.g. ?request=GetPassage&urn=...
+// Its TextRepository is fixed by NewHandler, which lists the valid references of every Work once.
+// Create a new Handler to serve changed texts.
+type Handler struct {
+	repository gocite.TextRepository
+	reffs      map[string][][]string
+}
+
+// NewHandler returns a Handler serving the texts of repo.
+// The passages and citation nodes of every Work are listed once for GetPrevNextUrn and GetFirstUrn.
+func NewHandler(repo gocite.TextRepository) *Handler {
+	h := &Handler{repository: repo, reffs: map[string][][]string{}}
+	for _, group := range repo.Textgroups {
+		for _, work := range group.Works {
+			levels := [][]string{}
+			for depth := 0; depth <= gocite.CitationDepth(work); depth++ {
+				reff, err := gocite.GetValidReff(work.WorkID, depth, work)
+				if err != nil {
+					break
+				}
+				levels = append(levels, reff)
+			}
+			h.reffs[work.WorkID] = levels
+		}
+	}
+	return h
+}
+
+// CTSError is a CTS API error, written as a CTSError document
+type CTSError struct {
+	XMLName xml.Name `xml:"http://chs.harvard.edu/xmlns/cts CTSError"`
+	Message string   `xml:"message"`
+	Code    int      `xml:"code"`
+}
+
+func (e *CTSError) Error() string {
+	return "CTS error " + strconv.Itoa(e.Code) + ": " + e.Message
+}
+
+// ctsRequest echoes the parameters of a request in the response
+type ctsRequest struct {
+	RequestName  string `xml:"requestName"`
+	RequestURN   string `xml:"requestUrn,omitempty"`
+	RequestLevel string `xml:"requestLevel,omitempty"`
+}
+
+// ctsResponse is the document returned for all successful requests
+type ctsResponse struct {
+	XMLName xml.Name
+	Request ctsRequest  `xml:"request"`
+	Reply   interface{} `xml:"reply"`
+}
+
+// TextInventory lists the texts of the repository, grouped by textgroup and notional work
+type TextInventory struct {
+	Version    string         `xml:"tiversion,attr"`
+	Textgroups []InvTextgroup `xml:"textgroup"`
+}
+
+// InvTextgroup is a textgroup in a TextInventory
+type InvTextgroup struct {
+	URN       string    `xml:"urn,attr"`
+	GroupName string    `xml:"groupname"`
+	Works     []InvWork `xml:"work"`
+}
+
+// InvWork is a notional work in a TextInventory
+type InvWork struct {
+	URN      string       `xml:"urn,attr"`
+	Language string       `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"`
+	Title    string       `xml:"title"`
+	Editions []InvVersion `xml:"edition"`
+}
+
+// InvVersion is a version of a work in a TextInventory
+type InvVersion struct {
+	URN       string        `xml:"urn,attr"`
+	Label     string        `xml:"label"`
+	Citation  string        `xml:"citationScheme,omitempty"`
+	Exemplars []InvExemplar `xml:"exemplar"`
+}
+
+// InvExemplar is an exemplar of a version in a TextInventory
+type InvExemplar struct {
+	URN      string `xml:"urn,attr"`
+	Label    string `xml:"label"`
+	Citation string `xml:"citationScheme,omitempty"`
+}
+
+// Label is the human readable description of a URN returned by GetLabel and GetPassagePlus
+type Label struct {
+	GroupName string `xml:"groupname"`
+	Title     string `xml:"title"`
+	Version   string `xml:"version"`
+	Exemplar  string `xml:"exemplar,omitempty"`
+	Citation  string `xml:"citation,omitempty"`
+}
+
+// PrevNext holds the URNs of the preceding and following passages of the same size
+type PrevNext struct {
+	Prev string `xml:"prev>urn"`
+	Next string `xml:"next>urn"`
+}
+
+// PassageNode is a passage of a GetPassage reply in a TEI wrapper
+type PassageNode struct {
+	TEI struct {
+		XMLName xml.Name `xml:"http://www.tei-c.org/ns/1.0 TEI"`
+		Div     struct {
+			Type string `xml:"type,attr"`
+			N    string `xml:"n,attr"`
+			Ab   []Ab   `xml:"ab"`
+		} `xml:"text>body>div"`
+	}
+}
+
+// Ab holds the text of a single cited passage
+type Ab struct {
+	N    string `xml:"n,attr"`
+	Text string `xml:",chardata"`
+}
+
+type reffReply struct {
+	Reff []string `xml:"reff>urn"`
+}
+
+type passageReply struct {
+	URN     string      `xml:"urn"`
+	Passage PassageNode `xml:"passage"`
+}
+
+type passagePlusReply struct {
+	URN      string      `xml:"urn"`
+	Label    Label       `xml:"label"`
+	Passage  PassageNode `xml:"passage"`
+	PrevNext PrevNext    `xml:"prevnext"`
+}
+
+type prevNextReply struct {
+	URN      string   `xml:"urn"`
+	PrevNext PrevNext `xml:"prevnext"`
+}
+
+type urnReply struct {
+	URN string `xml:"urn"`
+}
+
+type labelReply struct {
+	URN   string `xml:"urn"`
+	Label Label  `xml:"label"`
+}
+
+type capabilitiesReply struct {
+	Inventory TextInventory `xml:"TextInventory"`
+}
+
+// ServeHTTP answers a CTS API request
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := ctsRequest{RequestName: query.Get("request"), RequestURN: query.Get("urn"), RequestLevel: query.Get("level")}
+	reply, err := h.reply(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeXML(w, http.StatusOK, ctsResponse{XMLName: xml.Name{Space: ctsNS, Local: req.RequestName}, Request: req, Reply: reply})
+}
+
+// reply builds the reply of a request
+func (h *Handler) reply(req ctsRequest) (interface{}, error) {
+	if req.RequestName == "GetCapabilities" {
+		return capabilitiesReply{Inventory: h.Inventory()}, nil
+	}
+	if !knownRequest(req.RequestName) {
+		return nil, &CTSError{Code: InvalidRequestName, Message: "Invalid request name " + strconv.Quote(req.RequestName)}
+	}
+	urn, err := gocite.ParseCTSURN(req.RequestURN)
+	if err != nil {
+		return nil, &CTSError{Code: InvalidURNSyntax, Message: err.Error()}
+	}
+	work, resolved, err := h.repository.ResolveURN(req.RequestURN)
+	if err != nil {
+		return nil, &CTSError{Code: InvalidURNReference, Message: err.Error()}
+	}
+	switch req.RequestName {
+	case "GetValidReff":
+		reff, err := h.validReff(work, resolved, req.RequestLevel)
+		return reffReply{Reff: reff}, err
+	case "GetPassage":
+		passage, err := h.passage(resolved)
+		return passageReply{URN: req.RequestURN, Passage: passage}, err
+	case "GetPassagePlus":
+		passage, err := h.passage(resolved)
+		if err != nil {
+			return nil, err
+		}
+		prevNext, err := h.prevNext(work, resolved)
+		return passagePlusReply{URN: req.RequestURN, Label: h.label(urn), Passage: passage, PrevNext: prevNext}, err
+	case "GetPrevNextUrn":
+		prevNext, err := h.prevNext(work, resolved)
+		return prevNextReply{URN: req.RequestURN, PrevNext: prevNext}, err
+	case "GetFirstUrn":
+		first, err := h.firstURN(work, resolved)
+		return urnReply{URN: first}, err
+	default:
+		if _, err := h.repository.GetPassages(resolved); err != nil {
+			return nil, &CTSError{Code: InvalidURNReference, Message: err.Error()}
+		}
+		return labelReply{URN: req.RequestURN, Label: h.label(urn)}, nil
+	}
+}
+
+func knownRequest(name string) bool {
+	switch name {
+	case "GetValidReff", "GetPassage", "GetPassagePlus", "GetPrevNextUrn", "GetFirstUrn", "GetLabel":
+		return true
+	}
+	return false
+}
+
+// Inventory returns the TextInventory of the repository.
+// Works are described by their CatalogEntry where the catalog has one.
+// Works cited without a version, e.g. urn:cts:latinLit:phi0959.phi006:, are listed as an edition
+// of themselves, as their passages are served at the URN of the notional work.
+func (h *Handler) Inventory() TextInventory {
+	inventory := TextInventory{Version: "5.0.rc.1"}
+	for _, group := range h.repository.Textgroups {
+		invGroup := InvTextgroup{URN: group.TextgroupID}
+		for _, work := range group.Works {
+			urn := gocite.SplitCTS(work.WorkID)
+			entry, _ := h.repository.GetCatalogEntry(work.WorkID)
+			if invGroup.GroupName == "" {
+				invGroup.GroupName = entry.GroupName
+			}
+			notional := urn.DropVersion().ID
+			wi := -1
+			for i := range invGroup.Works {
+				if invGroup.Works[i].URN == notional {
+					wi = i
+				}
+			}
+			if wi == -1 {
+				invGroup.Works = append(invGroup.Works, InvWork{URN: notional, Language: entry.Language, Title: entry.WorkTitle})
+				wi = len(invGroup.Works) - 1
+			}
+			invWork := &invGroup.Works[wi]
+			switch urn.WorkLevel() {
+			case 2, 3:
+				invWork.Editions = append(invWork.Editions, InvVersion{URN: work.WorkID, Label: entry.VersionLabel, Citation: entry.CitationScheme})
+			case 4:
+				version := urn.DropExemplar().ID
+				vi := -1
+				for i := range invWork.Editions {
+					if invWork.Editions[i].URN == version {
+						vi = i
+					}
+				}
+				if vi == -1 {
+					invWork.Editions = append(invWork.Editions, InvVersion{URN: version, Label: entry.VersionLabel})
+					vi = len(invWork.Editions) - 1
+				}
+				invWork.Editions[vi].Exemplars = append(invWork.Editions[vi].Exemplars,
+					InvExemplar{URN: work.WorkID, Label: entry.ExemplarLabel, Citation: entry.CitationScheme})
+			}
+		}
+		inventory.Textgroups = append(inventory.Textgroups, invGroup)
+	}
+	return inventory
+}
+
+// validReff returns the URNs of the citation nodes at the requested level within resolved.
+// Without a level, the URNs of all passages are returned.
+func (h *Handler) validReff(work gocite.Work, resolved, levelString string) ([]string, error) {
+	level := 0
+	if levelString != "" {
+		var err error
+		level, err = strconv.Atoi(levelString)
+		if err != nil || level < 1 || level > gocite.CitationDepth(work) {
+			return nil, &CTSError{Code: InvalidLevel, Message: "Invalid level " + strconv.Quote(levelString)}
+		}
+	}
+	reff, err := gocite.GetValidReff(resolved, level, work)
+	if err != nil {
+		return nil, &CTSError{Code: InvalidURNReference, Message: err.Error()}
+	}
+	return reff, nil
+}
+
+// passage returns the text resolved refers to, one Ab per passage
+func (h *Handler) passage(resolved string) (PassageNode, error) {
+	node := PassageNode{}
+	node.TEI.Div.Type = "edition"
+	node.TEI.Div.N = gocite.SplitCTS(resolved).Passage
+	texts, err := h.repository.ExtractTextByID(resolved)
+	if err != nil {
+		return node, &CTSError{Code: InvalidURNReference, Message: err.Error()}
+	}
+	for _, text := range texts {
+		node.TEI.Div.Ab = append(node.TEI.Div.Ab, Ab{N: gocite.SplitCTS(text.ID).Passage, Text: text.Text})
+	}
+	return node, nil
+}
+
+// prevNext returns the URNs of the passages preceding and following resolved
+// at the same citation level and spanning the same number of citation nodes.
+// The ends of a range citing different levels, e.g. 1.590-2, are resolved at the deeper level.
+func (h *Handler) prevNext(work gocite.Work, resolved string) (PrevNext, error) {
+	urn := gocite.SplitCTS(resolved).DropSubref()
+	if urn.Passage == "" {
+		return PrevNext{}, nil
+	}
+	end := urn.Start
+	if urn.Range {
+		end = urn.End
+	}
+	depth := len(urn.Start.Citation)
+	if len(end.Citation) > depth {
+		depth = len(end.Citation)
+	}
+	reff, err := h.workReff(work, depth)
+	if err != nil {
+		return PrevNext{}, &CTSError{Code: InvalidURNReference, Message: err.Error()}
+	}
+	base := urn.DropPassage().ID
+	first, last := -1, -1
+	for i, ref := range reff {
+		if first == -1 && within(ref, base+urn.Start.ID) {
+			first = i
+		}
+		if within(ref, base+end.ID) {
+			last = i
+		}
+	}
+	if first == -1 || last < first {
+		return PrevNext{}, &CTSError{Code: InvalidURNReference, Message: "passage " + urn.Passage + " not found"}
+	}
+	size := last - first + 1
+	prevNext := PrevNext{}
+	if first-size >= 0 {
+		prevNext.Prev = span(reff[first-size : first])
+	}
+	if last+size < len(reff) {
+		prevNext.Next = span(reff[last+1 : last+1+size])
+	}
+	return prevNext, nil
+}
+
+// firstURN returns the URN of the first passage of the Work without a passage,
+// or else the first citation node with the same parent and at the same level as resolved
+func (h *Handler) firstURN(work gocite.Work, resolved string) (string, error) {
+	urn := gocite.SplitCTS(resolved).DropSubref()
+	if _, err := h.repository.GetPassages(urn.ID); err != nil {
+		return "", &CTSError{Code: InvalidURNReference, Message: err.Error()}
+	}
+	parent := urn.DropPassage().ID
+	level := len(urn.Start.Citation)
+	if level > 1 {
+		parent += strings.Join(urn.Start.Citation[:level-1], ".")
+	}
+	reff, err := h.workReff(work, level)
+	if err == nil {
+		for _, ref := range reff {
+			if level <= 1 || strings.HasPrefix(ref, parent+".") {
+				return ref, nil
+			}
+		}
+	}
+	return "", &CTSError{Code: InvalidURNReference, Message: "no passages found in " + parent}
+}
+
+// workReff returns the URNs of all citation nodes of a Work at depth, or of all passages at depth 0,
+// from the lists NewHandler has prepared if there are any
+func (h *Handler) workReff(work gocite.Work, depth int) ([]string, error) {
+	if levels, found := h.reffs[work.WorkID]; found && depth >= 0 && depth < len(levels) {
+		return levels[depth], nil
+	}
+	return gocite.GetValidReff(work.WorkID, depth, work)
+}
+
+// label describes urn with the CatalogEntry of its Work
+func (h *Handler) label(urn gocite.CTSURN) Label {
+	entry, _ := h.repository.GetCatalogEntry(urn.ID)
+	return Label{GroupName: entry.GroupName, Title: entry.WorkTitle, Version: entry.VersionLabel,
+		Exemplar: entry.ExemplarLabel, Citation: urn.Passage}
+}
+
+// span returns the URN of a single reference or the range from the first to the last reference
+func span(reff []string) string {
+	if len(reff) == 1 {
+		return reff[0]
+	}
+	return reff[0] + "-" + gocite.SplitCTS(reff[len(reff)-1]).Passage
+}
+
+// within tests whether ref is the citation node node or one it contains
+func within(ref, node string) bool {
+	return ref == node || strings.HasPrefix(ref, node+".")
+}
+
+// writeError writes err as a CTSError document. Errors of the request
+// are answered with 400 Bad Request, unknown references with 404 Not Found.
+func writeError(w http.ResponseWriter, err error) {
+	ctsErr, ok := err.(*CTSError)
+	if !ok {
+		ctsErr = &CTSError{Code: InvalidURNReference, Message: err.Error()}
+	}
+	status := http.StatusBadRequest
+	if ctsErr.Code == InvalidURNReference {
+		status = http.StatusNotFound
+	}
+	writeXML(w, status, ctsErr)
+}
+
+func writeXML(w http.ResponseWriter, status int, v interface{}) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "%s%s", xml.Header, body)
+}
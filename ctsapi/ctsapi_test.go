@@ -0,0 +1,148 @@
+package ctsapi_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+	"github.com/ThomasK81/gocite/ctsapi"
+)
+
+var testCEX = `#!ctscatalog
+urn#citationScheme#groupName#workTitle#versionLabel#exemplarLabel#online#lang
+urn:cts:greekLit:tlg0012.tlg001.msA:#book,line#Homeric epic#Iliad#HMT project diplomatic edition##true#grc
+
+#!ctsdata
+urn:cts:greekLit:tlg0012.tlg001.msA:1.1#Μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος
+urn:cts:greekLit:tlg0012.tlg001.msA:1.2#οὐλομένην, ἣ μυρί' Ἀχαιοῖς ἄλγε' ἔθηκε,
+urn:cts:greekLit:tlg0012.tlg001.msA:1.3#πολλὰς δ' ἰφθίμους ψυχὰς Ἄϊδι προΐαψεν
+urn:cts:greekLit:tlg0012.tlg001.msA:2.1#Ἄλλοι μέν ῥα θεοί τε καὶ ἀνέρες ἱπποκορυσταὶ
+urn:cts:greekLit:tlg0012.tlg001.msA:2.2#εὗδον παννύχιοι, Δία δ' οὐκ ἔχε νήδυμος ὕπνος,
+`
+
+// response collects the parts of all CTS replies the tests look at
+type response struct {
+	XMLName   xml.Name
+	Reff      []string             `xml:"reply>reff>urn"`
+	URN       string               `xml:"reply>urn"`
+	Ab        []string             `xml:"reply>passage>TEI>text>body>div>ab"`
+	Prev      string               `xml:"reply>prevnext>prev>urn"`
+	Next      string               `xml:"reply>prevnext>next>urn"`
+	Label     string               `xml:"reply>label>groupname"`
+	Inventory ctsapi.TextInventory `xml:"reply>TextInventory"`
+	Message   string               `xml:"message"`
+	Code      int                  `xml:"code"`
+}
+
+func testServer(t *testing.T) *httptest.Server {
+	data, err := gocite.ReadCEX(strings.NewReader(testCEX))
+	if err != nil {
+		t.Fatal("Error calling ReadCEX: ", err)
+	}
+	return httptest.NewServer(ctsapi.NewHandler(gocite.NewTextRepository(data)))
+}
+
+func get(t *testing.T, server *httptest.Server, query url.Values) (int, response) {
+	resp, err := http.Get(server.URL + "?" + query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var result response
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal("Error decoding response: ", err)
+	}
+	return resp.StatusCode, result
+}
+
+func TestHandler(t *testing.T) {
+	server := testServer(t)
+	defer server.Close()
+	work := "urn:cts:greekLit:tlg0012.tlg001.msA:"
+	tests := []struct {
+		query    url.Values
+		expected response
+	}{
+		{query: url.Values{"request": {"GetCapabilities"}},
+			expected: response{Inventory: ctsapi.TextInventory{Version: "5.0.rc.1", Textgroups: []ctsapi.InvTextgroup{{
+				URN: "urn:cts:greekLit:tlg0012:", GroupName: "Homeric epic", Works: []ctsapi.InvWork{{
+					URN: "urn:cts:greekLit:tlg0012.tlg001:", Language: "grc", Title: "Iliad", Editions: []ctsapi.InvVersion{{
+						URN: work, Label: "HMT project diplomatic edition", Citation: "book,line"}}}}}}}}},
+		{query: url.Values{"request": {"GetValidReff"}, "urn": {"urn:cts:greekLit:tlg0012.tlg001:"}, "level": {"1"}},
+			expected: response{Reff: []string{work + "1", work + "2"}}},
+		{query: url.Values{"request": {"GetValidReff"}, "urn": {work + "1"}},
+			expected: response{Reff: []string{work + "1.1", work + "1.2", work + "1.3"}}},
+		{query: url.Values{"request": {"GetPassage"}, "urn": {work + "1.3-2.1"}},
+			expected: response{URN: work + "1.3-2.1", Ab: []string{"πολλὰς δ' ἰφθίμους ψυχὰς Ἄϊδι προΐαψεν", "Ἄλλοι μέν ῥα θεοί τε καὶ ἀνέρες ἱπποκορυσταὶ"}}},
+		{query: url.Values{"request": {"GetPassage"}, "urn": {work + "2"}},
+			expected: response{URN: work + "2", Ab: []string{"Ἄλλοι μέν ῥα θεοί τε καὶ ἀνέρες ἱπποκορυσταὶ", "εὗδον παννύχιοι, Δία δ' οὐκ ἔχε νήδυμος ὕπνος,"}}},
+		{query: url.Values{"request": {"GetPassagePlus"}, "urn": {work + "1.2"}},
+			expected: response{URN: work + "1.2", Ab: []string{"οὐλομένην, ἣ μυρί' Ἀχαιοῖς ἄλγε' ἔθηκε,"},
+				Prev: work + "1.1", Next: work + "1.3", Label: "Homeric epic"}},
+		{query: url.Values{"request": {"GetPrevNextUrn"}, "urn": {work + "1.2-1.3"}},
+			expected: response{URN: work + "1.2-1.3", Next: work + "2.1-2.2"}},
+		{query: url.Values{"request": {"GetPrevNextUrn"}, "urn": {work + "2"}},
+			expected: response{URN: work + "2", Prev: work + "1"}},
+		{query: url.Values{"request": {"GetPrevNextUrn"}, "urn": {work + "1-1.2"}},
+			expected: response{URN: work + "1-1.2", Next: work + "1.3-2.1"}},
+		{query: url.Values{"request": {"GetPrevNextUrn"}, "urn": {work + "2.1-2"}},
+			expected: response{URN: work + "2.1-2", Prev: work + "1.2-1.3"}},
+		{query: url.Values{"request": {"GetFirstUrn"}, "urn": {work + "2.2"}},
+			expected: response{URN: work + "2.1"}},
+		{query: url.Values{"request": {"GetFirstUrn"}, "urn": {work}},
+			expected: response{URN: work + "1.1"}},
+		{query: url.Values{"request": {"GetLabel"}, "urn": {work + "1.1"}},
+			expected: response{URN: work + "1.1", Label: "Homeric epic"}},
+	}
+	for _, test := range tests {
+		status, result := get(t, server, test.query)
+		if status != http.StatusOK {
+			t.Error("For", test.query, "expected status 200, got", status, result.Message)
+			continue
+		}
+		test.expected.XMLName = xml.Name{Space: "http://chs.harvard.edu/xmlns/cts", Local: test.query.Get("request")}
+		if !reflect.DeepEqual(result, test.expected) {
+			t.Error("For", test.query, "expected", test.expected, "got", result)
+		}
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	server := testServer(t)
+	defer server.Close()
+	tests := []struct {
+		query  url.Values
+		status int
+		code   int
+	}{
+		{query: url.Values{"request": {"GetText"}}, status: http.StatusBadRequest, code: ctsapi.InvalidRequestName},
+		{query: url.Values{"request": {"GetPassage"}, "urn": {"urn:cts:greekLit:tlg0012.tlg001.msA"}}, status: http.StatusBadRequest, code: ctsapi.InvalidURNSyntax},
+		{query: url.Values{"request": {"GetPassage"}, "urn": {"urn:cts:greekLit:tlg0012.tlg001.msA:3.1"}}, status: http.StatusNotFound, code: ctsapi.InvalidURNReference},
+		{query: url.Values{"request": {"GetPassage"}, "urn": {"urn:cts:latinLit:phi0959.phi006:1.1"}}, status: http.StatusNotFound, code: ctsapi.InvalidURNReference},
+		{query: url.Values{"request": {"GetValidReff"}, "urn": {"urn:cts:greekLit:tlg0012.tlg001.msA:"}, "level": {"3"}}, status: http.StatusBadRequest, code: ctsapi.InvalidLevel},
+	}
+	for _, test := range tests {
+		status, result := get(t, server, test.query)
+		if status != test.status || result.Code != test.code || result.XMLName.Local != "CTSError" {
+			t.Error("For", test.query, "expected", test.status, test.code, "got", status, result)
+		}
+	}
+}
+
+func TestInventoryNotionalWork(t *testing.T) {
+	data, err := gocite.ReadCEX(strings.NewReader("#!ctsdata\nurn:cts:latinLit:phi0959.phi006:1.1#In nova fert animus mutatas dicere formas\n"))
+	if err != nil {
+		t.Fatal("Error calling ReadCEX: ", err)
+	}
+	inventory := ctsapi.NewHandler(gocite.NewTextRepository(data)).Inventory()
+	expected := []ctsapi.InvTextgroup{{URN: "urn:cts:latinLit:phi0959:", Works: []ctsapi.InvWork{{
+		URN: "urn:cts:latinLit:phi0959.phi006:", Editions: []ctsapi.InvVersion{{URN: "urn:cts:latinLit:phi0959.phi006:"}}}}}}
+	if !reflect.DeepEqual(inventory.Textgroups, expected) {
+		t.Error("expected", expected, "got", inventory.Textgroups)
+	}
+}
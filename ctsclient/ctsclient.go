@@ -0,0 +1,287 @@
+// Package ctsclient retrieves texts from remote CTS API endpoints
+// and turns the responses into gocite Textgroups, Works and Passages.
+package ctsclient
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ThomasK81/gocite"
+	"github.com/ThomasK81/gocite/ctsapi"
+)
+
+// Client issues CTS API requests against the endpoint at BaseURL.
+// Requests failing with a network error or a 5xx or 429 status code are retried
+// up to Retries times, waiting RetryWait times the number of the attempt in between.
+// GetWork requests the passages of a Work in pages of PageSize citation nodes.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	Retries    int
+	RetryWait  time.Duration
+	PageSize   int
+}
+
+// NewClient returns a Client for the CTS endpoint at baseURL with default settings
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: baseURL, HTTPClient: http.DefaultClient, Retries: 3, RetryWait: 500 * time.Millisecond, PageSize: 100}
+}
+
+// GetCapabilities requests the text inventory of the endpoint and returns it as a TextRepository
+// holding a CatalogEntry and an empty Work for every edition and exemplar
+func (c *Client) GetCapabilities(ctx context.Context) (gocite.TextRepository, error) {
+	var reply struct {
+		Inventory ctsapi.TextInventory `xml:"reply>TextInventory"`
+	}
+	if err := c.get(ctx, url.Values{"request": {"GetCapabilities"}}, &reply); err != nil {
+		return gocite.TextRepository{}, err
+	}
+	repo := gocite.TextRepository{}
+	for _, group := range reply.Inventory.Textgroups {
+		textgroup := gocite.Textgroup{TextgroupID: group.URN}
+		for _, work := range group.Works {
+			for _, edition := range work.Editions {
+				entry := gocite.CatalogEntry{URN: edition.URN, CitationScheme: edition.Citation, GroupName: group.GroupName,
+					WorkTitle: work.Title, VersionLabel: edition.Label, Online: true, Language: work.Language}
+				if len(edition.Exemplars) == 0 {
+					repo.Catalog = append(repo.Catalog, entry)
+					textgroup.Works = append(textgroup.Works, gocite.Work{WorkID: edition.URN})
+				}
+				for _, exemplar := range edition.Exemplars {
+					entry.URN, entry.ExemplarLabel = exemplar.URN, exemplar.Label
+					if exemplar.Citation != "" {
+						entry.CitationScheme = exemplar.Citation
+					}
+					repo.Catalog = append(repo.Catalog, entry)
+					textgroup.Works = append(textgroup.Works, gocite.Work{WorkID: exemplar.URN})
+				}
+			}
+		}
+		repo.Textgroups = append(repo.Textgroups, textgroup)
+	}
+	return repo, nil
+}
+
+// GetValidReff requests the URNs of the citation nodes of a URN at the given citation level.
+// Level 0 leaves the level to the endpoint.
+func (c *Client) GetValidReff(ctx context.Context, URNString string, level int) ([]string, error) {
+	query := url.Values{"request": {"GetValidReff"}, "urn": {URNString}}
+	if level > 0 {
+		query.Set("level", strconv.Itoa(level))
+	}
+	var reply struct {
+		Reff []string `xml:"reply>reff>urn"`
+	}
+	if err := c.get(ctx, query, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Reff, nil
+}
+
+// GetPassage requests the text of a URN and returns it as a Work with one linked Passage
+// per citation node found in the reply. Citation nodes are the elements carrying an n attribute,
+// so that e.g. <div n="1"><l n="2"> becomes the Passage 1.2. A reply without such elements
+// becomes a single Passage with the requested URN.
+func (c *Client) GetPassage(ctx context.Context, URNString string) (gocite.Work, error) {
+	urn, err := gocite.ParseCTSURN(URNString)
+	if err != nil {
+		return gocite.Work{}, err
+	}
+	var reply struct {
+		Passage struct {
+			Inner []byte `xml:",innerxml"`
+		} `xml:"reply>passage"`
+	}
+	if err := c.get(ctx, url.Values{"request": {"GetPassage"}, "urn": {URNString}}, &reply); err != nil {
+		return gocite.Work{}, err
+	}
+	texts, err := citationNodes(reply.Passage.Inner, urn)
+	if err != nil {
+		return gocite.Work{}, errors.New("GetPassage: " + err.Error())
+	}
+	return linkedWork(urn.DropPassage().ID, texts), nil
+}
+
+// GetWork requests all passages of a URN, PageSize citation nodes at a time,
+// and returns them as a single Work with linked Passages
+func (c *Client) GetWork(ctx context.Context, URNString string) (gocite.Work, error) {
+	urn, err := gocite.ParseCTSURN(URNString)
+	if err != nil {
+		return gocite.Work{}, err
+	}
+	reff, err := c.GetValidReff(ctx, URNString, 0)
+	if err != nil {
+		return gocite.Work{}, err
+	}
+	pageSize := c.PageSize
+	if pageSize < 1 {
+		pageSize = 1
+	}
+	texts := []gocite.TextAndID{}
+	for start := 0; start < len(reff); start += pageSize {
+		end := start + pageSize - 1
+		if end >= len(reff) {
+			end = len(reff) - 1
+		}
+		page := reff[start]
+		if end > start {
+			page += "-" + gocite.SplitCTS(reff[end]).Passage
+		}
+		work, err := c.GetPassage(ctx, page)
+		if err != nil {
+			return gocite.Work{}, err
+		}
+		for _, p := range work.Passages {
+			text, _ := gocite.PassageText(p)
+			texts = append(texts, gocite.TextAndID{ID: p.PassageID, Text: text})
+		}
+	}
+	return linkedWork(urn.DropPassage().ID, texts), nil
+}
+
+// citationNodes collects the text of the elements with an n attribute in a TEI passage.
+// The n attributes of nested elements are joined by dots, the n attributes of
+// edition, translation and commentary divs are left out.
+func citationNodes(passage []byte, urn gocite.CTSURN) ([]gocite.TextAndID, error) {
+	decoder := xml.NewDecoder(strings.NewReader(string(passage)))
+	stack := []string{}
+	texts := []gocite.TextAndID{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			n := ""
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "type" && (attr.Value == "edition" || attr.Value == "translation" || attr.Value == "commentary") {
+					n = ""
+					break
+				}
+				if attr.Name.Local == "n" {
+					n = attr.Value
+				}
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			text := strings.Join(strings.Fields(string(t)), " ")
+			if text == "" {
+				continue
+			}
+			ref := []string{}
+			for _, n := range stack {
+				if n != "" {
+					ref = append(ref, n)
+				}
+			}
+			id := urn.DropPassage().ID + strings.Join(ref, ".")
+			if len(ref) == 0 {
+				id = urn.ID
+			}
+			if len(texts) > 0 && texts[len(texts)-1].ID == id {
+				texts[len(texts)-1].Text += " " + text
+				continue
+			}
+			texts = append(texts, gocite.TextAndID{ID: id, Text: text})
+		}
+	}
+	return texts, nil
+}
+
+// linkedWork builds an ordered Work with linked Passages from texts in document order
+func linkedWork(workID string, texts []gocite.TextAndID) gocite.Work {
+	work := gocite.Work{WorkID: workID, Ordered: true}
+	for i, text := range texts {
+		passage := gocite.Passage{PassageID: text.ID, Range: gocite.IsRange(text.ID), Index: i,
+			Analysis: []gocite.Tokenisation{gocite.NewTextTokenisation(text.Text)}}
+		if i > 0 {
+			passage.Prev = gocite.PassLoc{Exists: true, PassageID: texts[i-1].ID, Index: i - 1}
+		}
+		if i < len(texts)-1 {
+			passage.Next = gocite.PassLoc{Exists: true, PassageID: texts[i+1].ID, Index: i + 1}
+		}
+		work.Passages = append(work.Passages, passage)
+	}
+	if len(texts) > 0 {
+		work.First = gocite.PassLoc{Exists: true, PassageID: texts[0].ID, Index: 0}
+		work.Last = gocite.PassLoc{Exists: true, PassageID: texts[len(texts)-1].ID, Index: len(texts) - 1}
+	}
+	return gocite.BuildIDIndex(work)
+}
+
+// get issues a request with the given query parameters, retrying where it makes sense,
+// and decodes the reply into v. A CTSError document is returned as *ctsapi.CTSError.
+func (c *Client) get(ctx context.Context, query url.Values, v interface{}) error {
+	endpoint, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return err
+	}
+	params := endpoint.Query()
+	for key, values := range query {
+		params[key] = values
+	}
+	endpoint.RawQuery = params.Encode()
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	var body []byte
+	var status int
+	for attempt := 0; ; attempt++ {
+		body, status, err = c.do(ctx, httpClient, endpoint.String())
+		retry := err != nil || status >= 500 || status == http.StatusTooManyRequests
+		if !retry || attempt >= c.Retries || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.RetryWait * time.Duration(attempt+1)):
+		}
+	}
+	if err != nil {
+		return err
+	}
+	var root struct {
+		XMLName xml.Name
+	}
+	if xml.Unmarshal(body, &root) == nil && root.XMLName.Local == "CTSError" {
+		ctsErr := &ctsapi.CTSError{}
+		if err := xml.Unmarshal(body, ctsErr); err != nil {
+			return err
+		}
+		return ctsErr
+	}
+	if status != http.StatusOK {
+		return errors.New("ctsclient: " + endpoint.String() + " returned " + http.StatusText(status))
+	}
+	return xml.Unmarshal(body, v)
+}
+
+// do issues a single GET request and returns the body and status code of the response
+func (c *Client) do(ctx context.Context, httpClient *http.Client, endpoint string) ([]byte, int, error) {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
+}
@@ -0,0 +1,126 @@
+package ctsclient_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ThomasK81/gocite"
+	"github.com/ThomasK81/gocite/ctsapi"
+	"github.com/ThomasK81/gocite/ctsclient"
+)
+
+var testCEX = `#!ctscatalog
+urn#citationScheme#groupName#workTitle#versionLabel#exemplarLabel#online#lang
+urn:cts:greekLit:tlg0012.tlg001.msA:#book,line#Homeric epic#Iliad#HMT project diplomatic edition##true#grc
+
+#!ctsdata
+urn:cts:greekLit:tlg0012.tlg001.msA:1.1#Μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος
+urn:cts:greekLit:tlg0012.tlg001.msA:1.2#οὐλομένην, ἣ μυρί' Ἀχαιοῖς ἄλγε' ἔθηκε,
+urn:cts:greekLit:tlg0012.tlg001.msA:1.3#πολλὰς δ' ἰφθίμους ψυχὰς Ἄϊδι προΐαψεν
+urn:cts:greekLit:tlg0012.tlg001.msA:2.1#Ἄλλοι μέν ῥα θεοί τε καὶ ἀνέρες ἱπποκορυσταὶ
+urn:cts:greekLit:tlg0012.tlg001.msA:2.2#εὗδον παννύχιοι, Δία δ' οὐκ ἔχε νήδυμος ὕπνος,
+`
+
+const workID = "urn:cts:greekLit:tlg0012.tlg001.msA:"
+
+func testServer(t *testing.T) (*httptest.Server, gocite.CEXData) {
+	data, err := gocite.ReadCEX(strings.NewReader(testCEX))
+	if err != nil {
+		t.Fatal("Error calling ReadCEX: ", err)
+	}
+	return httptest.NewServer(ctsapi.NewHandler(gocite.NewTextRepository(data))), data
+}
+
+func TestGetCapabilities(t *testing.T) {
+	server, data := testServer(t)
+	defer server.Close()
+	repo, err := ctsclient.NewClient(server.URL).GetCapabilities(context.Background())
+	if err != nil {
+		t.Fatal("Error calling GetCapabilities: ", err)
+	}
+	if !reflect.DeepEqual(repo.Catalog, data.Catalog) {
+		t.Error("expected", data.Catalog, "got", repo.Catalog)
+	}
+	if len(repo.Textgroups) != 1 || len(repo.Textgroups[0].Works) != 1 || repo.Textgroups[0].Works[0].WorkID != workID {
+		t.Error("unexpected textgroups", repo.Textgroups)
+	}
+}
+
+func TestGetPassage(t *testing.T) {
+	server, _ := testServer(t)
+	defer server.Close()
+	client := ctsclient.NewClient(server.URL)
+	reff, err := client.GetValidReff(context.Background(), workID, 1)
+	if err != nil || !reflect.DeepEqual(reff, []string{workID + "1", workID + "2"}) {
+		t.Error("unexpected reff", reff, err)
+	}
+	work, err := client.GetPassage(context.Background(), workID+"1.3-2.1")
+	if err != nil {
+		t.Fatal("Error calling GetPassage: ", err)
+	}
+	if work.WorkID != workID || len(work.Passages) != 2 || !work.First.Exists || work.Last.PassageID != workID+"2.1" {
+		t.Fatal("unexpected work", work)
+	}
+	if next := gocite.GetNext(workID+"1.3", work); next.PassageID != workID+"2.1" {
+		t.Error("expected next passage 2.1, got", next.PassageID)
+	}
+	if text, _ := gocite.PassageText(work.Passages[0]); text != "πολλὰς δ' ἰφθίμους ψυχὰς Ἄϊδι προΐαψεν" {
+		t.Error("unexpected text", text)
+	}
+	_, err = client.GetPassage(context.Background(), workID+"3.1")
+	var ctsErr *ctsapi.CTSError
+	if !errors.As(err, &ctsErr) || ctsErr.Code != ctsapi.InvalidURNReference {
+		t.Error("expected CTS error 3, got", err)
+	}
+}
+
+func TestGetWork(t *testing.T) {
+	server, data := testServer(t)
+	defer server.Close()
+	client := ctsclient.NewClient(server.URL)
+	client.PageSize = 2
+	work, err := client.GetWork(context.Background(), workID)
+	if err != nil {
+		t.Fatal("Error calling GetWork: ", err)
+	}
+	expected := data.Textgroups[0].Works[0]
+	if len(work.Passages) != len(expected.Passages) {
+		t.Fatal("expected", len(expected.Passages), "passages, got", len(work.Passages))
+	}
+	for i := range work.Passages {
+		if !reflect.DeepEqual(work.Passages[i], expected.Passages[i]) {
+			t.Error("expected", expected.Passages[i], "got", work.Passages[i])
+		}
+	}
+}
+
+func TestRetries(t *testing.T) {
+	server, _ := testServer(t)
+	defer server.Close()
+	var calls int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.Redirect(w, r, server.URL+"?"+r.URL.RawQuery, http.StatusTemporaryRedirect)
+	}))
+	defer flaky.Close()
+	client := ctsclient.NewClient(flaky.URL)
+	client.RetryWait = time.Millisecond
+	if _, err := client.GetValidReff(context.Background(), workID, 0); err != nil {
+		t.Error("expected success after two retries, got", err)
+	}
+	atomic.StoreInt32(&calls, 0)
+	client.Retries = 1
+	if _, err := client.GetValidReff(context.Background(), workID, 0); err == nil {
+		t.Error("expected an error after one retry")
+	}
+}
@@ -0,0 +1,499 @@
+package gocite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// URNError reports why a URN string is not valid.
+// Component names the part of the URN that is wrong
+// ("urn", "base", "protocol", "namespace", "work", "passage" or "subreference")
+// and Offset is the byte offset in URN at which the problem was found.
+type URNError struct {
+	URN       string
+	Component string
+	Offset    int
+	Message   string
+}
+
+func (e *URNError) Error() string {
+	return fmt.Sprintf("invalid urn %q: %s at offset %d: %s", e.URN, e.Component, e.Offset, e.Message)
+}
+
+// ParseCTSURN parses a CTS URN string into a CTSURN, enforcing the CTS URN grammar.
+// If the string is not a valid CTS URN, the returned error is a *URNError
+// and the returned CTSURN is marked as InValid.
+func ParseCTSURN(URNString string) (CTSURN, error) {
+	if err := validateCTS(URNString); err != nil {
+		return CTSURN{ID: URNString, InValid: true}, err
+	}
+	return parseCTS(URNString), nil
+}
+
+// validateCTS checks a CTS URN string component by component
+// and returns a *URNError for the first problem it finds
+func validateCTS(URNString string) error {
+	comps := strings.Split(URNString, ":")
+	if len(comps) < 5 {
+		return &URNError{URN: URNString, Component: "urn", Offset: len(URNString),
+			Message: fmt.Sprintf("expected 5 colon-separated components, found %d", len(comps))}
+	}
+	offsets := make([]int, len(comps))
+	for i := 1; i < len(comps); i++ {
+		offsets[i] = offsets[i-1] + len(comps[i-1]) + 1
+	}
+	if len(comps) > 5 {
+		return &URNError{URN: URNString, Component: "passage", Offset: offsets[5] - 1, Message: "unexpected ':'"}
+	}
+	if comps[0] != "urn" {
+		return &URNError{URN: URNString, Component: "base", Offset: 0, Message: "expected \"urn\""}
+	}
+	if comps[1] != "cts" {
+		return &URNError{URN: URNString, Component: "protocol", Offset: offsets[1], Message: "expected \"cts\""}
+	}
+	if err := validateIdentifier(URNString, "namespace", comps[2], offsets[2], 1); err != nil {
+		return err
+	}
+	if err := validateIdentifier(URNString, "work", comps[3], offsets[3], 4); err != nil {
+		return err
+	}
+	return validatePassage(URNString, comps[4], offsets[4])
+}
+
+// validateIdentifier checks that s consists of at most maxParts non-empty
+// dot-separated parts made of letters, digits, '-' and '_'
+func validateIdentifier(URNString, component, s string, offset, maxParts int) error {
+	if s == "" {
+		return &URNError{URN: URNString, Component: component, Offset: offset, Message: "must not be empty"}
+	}
+	parts := 1
+	for i, r := range s {
+		switch {
+		case r == '.':
+			if maxParts == 1 {
+				return &URNError{URN: URNString, Component: component, Offset: offset + i, Message: "unexpected '.'"}
+			}
+			if i == 0 || s[i-1] == '.' || i == len(s)-1 {
+				return &URNError{URN: URNString, Component: component, Offset: offset + i, Message: "empty part"}
+			}
+			parts++
+			if parts > maxParts {
+				return &URNError{URN: URNString, Component: component, Offset: offset + i,
+					Message: fmt.Sprintf("more than %d parts", maxParts)}
+			}
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+		default:
+			return &URNError{URN: URNString, Component: component, Offset: offset + i,
+				Message: fmt.Sprintf("invalid character %q", r)}
+		}
+	}
+	return nil
+}
+
+// validatePassage checks the passage component of a CTS URN:
+// an empty passage, a single passage node or a range of two passage nodes
+func validatePassage(URNString, passage string, offset int) error {
+	if passage == "" {
+		return nil
+	}
+	hyphen := strings.Index(passage, "-")
+	if hyphen == -1 {
+		return validatePassageNode(URNString, passage, offset)
+	}
+	if second := strings.Index(passage[hyphen+1:], "-"); second != -1 {
+		return &URNError{URN: URNString, Component: "passage", Offset: offset + hyphen + 1 + second,
+			Message: "more than one range hyphen"}
+	}
+	if err := validatePassageNode(URNString, passage[:hyphen], offset); err != nil {
+		return err
+	}
+	return validatePassageNode(URNString, passage[hyphen+1:], offset+hyphen+1)
+}
+
+// validatePassageNode checks a single passage reference of the form
+// citation(.citation)*(@token([n])?)?
+func validatePassageNode(URNString, node string, offset int) error {
+	if node == "" {
+		return &URNError{URN: URNString, Component: "passage", Offset: offset, Message: "empty range end"}
+	}
+	citation := node
+	at := strings.Index(node, "@")
+	if at != -1 {
+		citation = node[:at]
+	}
+	if citation == "" {
+		return &URNError{URN: URNString, Component: "passage", Offset: offset, Message: "subreference without citation"}
+	}
+	for i, r := range citation {
+		switch {
+		case r == '.':
+			if i == 0 || citation[i-1] == '.' || i == len(citation)-1 {
+				return &URNError{URN: URNString, Component: "passage", Offset: offset + i, Message: "empty citation level"}
+			}
+		case !isPassageRune(r):
+			return &URNError{URN: URNString, Component: "passage", Offset: offset + i,
+				Message: fmt.Sprintf("invalid character %q", r)}
+		}
+	}
+	if at == -1 {
+		return nil
+	}
+	return validateSubreference(URNString, node[at+1:], offset+at+1)
+}
+
+// validateSubreference checks a subreference of the form token or token[n],
+// where n is a positive integer
+func validateSubreference(URNString, subref string, offset int) error {
+	open := -1
+	for i, r := range subref {
+		switch {
+		case r == '[':
+			if open != -1 {
+				return &URNError{URN: URNString, Component: "subreference", Offset: offset + i, Message: "nested '['"}
+			}
+			open = i
+		case r == ']':
+			if open == -1 {
+				return &URNError{URN: URNString, Component: "subreference", Offset: offset + i, Message: "unbalanced ']'"}
+			}
+			if i != len(subref)-1 {
+				return &URNError{URN: URNString, Component: "subreference", Offset: offset + i + 1,
+					Message: "unexpected characters after ']'"}
+			}
+		case open != -1:
+			if r < '0' || r > '9' {
+				return &URNError{URN: URNString, Component: "subreference", Offset: offset + i,
+					Message: fmt.Sprintf("invalid character %q in index", r)}
+			}
+		case r == '@':
+			return &URNError{URN: URNString, Component: "subreference", Offset: offset + i, Message: "unexpected '@'"}
+		case unicode.IsSpace(r) || unicode.IsControl(r) || r == '#' || r == '?' || r == '/' || r == '\\':
+			return &URNError{URN: URNString, Component: "subreference", Offset: offset + i,
+				Message: fmt.Sprintf("invalid character %q", r)}
+		}
+	}
+	switch {
+	case open == 0 || subref == "":
+		return &URNError{URN: URNString, Component: "subreference", Offset: offset, Message: "empty token"}
+	case open == -1:
+		return nil
+	case !strings.HasSuffix(subref, "]"):
+		return &URNError{URN: URNString, Component: "subreference", Offset: offset + open, Message: "unclosed '['"}
+	}
+	index := subref[open+1 : len(subref)-1]
+	if index == "" || index[0] == '0' {
+		return &URNError{URN: URNString, Component: "subreference", Offset: offset + open + 1,
+			Message: "index must be a positive integer"}
+	}
+	return nil
+}
+
+// isPassageRune reports whether r may be used in a citation level of a passage
+func isPassageRune(r rune) bool {
+	if r == utf8.RuneError || unicode.IsSpace(r) || unicode.IsControl(r) {
+		return false
+	}
+	return !strings.ContainsRune(":@-[]#?/\\.", r)
+}
+
+// parseCTS splits a CTS URN string with five colon-separated components
+// into all parts of a CTSURN. It does not check the components any further.
+func parseCTS(URNString string) CTSURN {
+	comps := strings.SplitN(URNString, ":", 5)
+	urn := CTSURN{ID: URNString,
+		Base:      comps[0],
+		Protocol:  comps[1],
+		Namespace: comps[2],
+		Work:      comps[3],
+		Passage:   comps[4]}
+	workParts := strings.SplitN(urn.Work, ".", 4)
+	for i := len(workParts); i < 4; i++ {
+		workParts = append(workParts, "")
+	}
+	urn.Textgroup, urn.WorkPart, urn.Version, urn.Exemplar = workParts[0], workParts[1], workParts[2], workParts[3]
+	if urn.Passage == "" {
+		return urn
+	}
+	rangeIndex := strings.Index(urn.Passage, "-")
+	if rangeIndex == -1 {
+		urn.Start = parsePassageNode(urn.Passage)
+		return urn
+	}
+	urn.Range = true
+	urn.Start = parsePassageNode(urn.Passage[:rangeIndex])
+	urn.End = parsePassageNode(urn.Passage[rangeIndex+1:])
+	return urn
+}
+
+// parsePassageNode splits a single passage reference like "1.1@μῆνιν[1]"
+// into its citation levels and its subreference
+func parsePassageNode(nodeString string) PassageNode {
+	node := PassageNode{ID: nodeString}
+	citation := nodeString
+	if at := strings.Index(nodeString, "@"); at != -1 {
+		citation = nodeString[:at]
+		node.Subref = parseSubreference(nodeString[at+1:])
+	}
+	if citation != "" {
+		node.Citation = strings.Split(citation, ".")
+	}
+	return node
+}
+
+// parseSubreference splits a subreference like "μῆνιν[1]" into its token and index.
+// A bracketed suffix that is not a positive number without leading zeros is kept as part of the token.
+func parseSubreference(subrefString string) Subreference {
+	subref := Subreference{Exists: true, Token: subrefString}
+	open := strings.LastIndex(subrefString, "[")
+	if open == -1 || !strings.HasSuffix(subrefString, "]") {
+		return subref
+	}
+	digits := subrefString[open+1 : len(subrefString)-1]
+	if digits == "" || digits[0] == '0' {
+		return subref
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil {
+		return subref
+	}
+	subref.Token = subrefString[:open]
+	subref.Index = n
+	return subref
+}
+
+// String returns the CTS URN string of a CTSURN,
+// built from its components.
+func (urn CTSURN) String() string {
+	if urn.InValid {
+		return urn.ID
+	}
+	return strings.Join([]string{urn.Base, urn.Protocol, urn.Namespace, urn.workString(), urn.passageString()}, ":")
+}
+
+// WorkLevel returns how deep a CTSURN points into the work hierarchy:
+// 1 for textgroup, 2 for work, 3 for version, 4 for exemplar, and 0 if there is no textgroup.
+func (urn CTSURN) WorkLevel() int {
+	switch {
+	case urn.Textgroup == "":
+		return 0
+	case urn.WorkPart == "":
+		return 1
+	case urn.Version == "":
+		return 2
+	case urn.Exemplar == "":
+		return 3
+	default:
+		return 4
+	}
+}
+
+// workString joins the work hierarchy of a CTSURN with dots
+func (urn CTSURN) workString() string {
+	parts := []string{urn.Textgroup, urn.WorkPart, urn.Version, urn.Exemplar}
+	for len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	return strings.Join(parts, ".")
+}
+
+// passageString joins the passage nodes of a CTSURN, using a hyphen for ranges
+func (urn CTSURN) passageString() string {
+	if urn.Range {
+		return urn.Start.String() + "-" + urn.End.String()
+	}
+	return urn.Start.String()
+}
+
+// withPassage returns the CTS URN string of urn with the passage component
+// replaced by passage
+func (urn CTSURN) withPassage(passage string) string {
+	return strings.Join([]string{urn.Base, urn.Protocol, urn.Namespace, urn.workString(), passage}, ":")
+}
+
+// String returns the passage reference of a PassageNode, e.g. "1.1@μῆνιν[1]"
+func (node PassageNode) String() string {
+	s := strings.Join(node.Citation, ".")
+	if node.Subref.Exists {
+		s = s + "@" + node.Subref.String()
+	}
+	return s
+}
+
+// CitationString returns the passage reference of a PassageNode
+// without its subreference, e.g. "1.1"
+func (node PassageNode) CitationString() string {
+	return strings.Join(node.Citation, ".")
+}
+
+// String returns a Subreference in the form token[n], omitting [n] if no index was given
+func (subref Subreference) String() string {
+	if !subref.Exists {
+		return ""
+	}
+	if subref.Index > 0 {
+		return subref.Token + "[" + strconv.Itoa(subref.Index) + "]"
+	}
+	return subref.Token
+}
+
+// DropPassage returns a copy of urn without its passage component,
+// e.g. urn:cts:greekLit:tlg0012.tlg001.msA: for urn:cts:greekLit:tlg0012.tlg001.msA:1.1
+func (urn CTSURN) DropPassage() CTSURN {
+	if urn.InValid {
+		return urn
+	}
+	urn.Start, urn.End, urn.Range = PassageNode{}, PassageNode{}, false
+	return urn.refresh()
+}
+
+// DropVersion returns a copy of urn reduced to the notional work level,
+// dropping both version and exemplar
+func (urn CTSURN) DropVersion() CTSURN {
+	if urn.InValid {
+		return urn
+	}
+	urn.Version, urn.Exemplar = "", ""
+	return urn.refresh()
+}
+
+// DropExemplar returns a copy of urn reduced to the version level
+func (urn CTSURN) DropExemplar() CTSURN {
+	if urn.InValid {
+		return urn
+	}
+	urn.Exemplar = ""
+	return urn.refresh()
+}
+
+// DropSubref returns a copy of urn without subreferences on its passage nodes
+func (urn CTSURN) DropSubref() CTSURN {
+	if urn.InValid {
+		return urn
+	}
+	urn.Start.Subref, urn.End.Subref = Subreference{}, Subreference{}
+	urn.Start.ID, urn.End.ID = urn.Start.String(), urn.End.String()
+	return urn.refresh()
+}
+
+// refresh recomputes the ID, Work and Passage strings of urn from its parts
+func (urn CTSURN) refresh() CTSURN {
+	urn.Work = urn.workString()
+	urn.Passage = urn.passageString()
+	urn.ID = urn.String()
+	return urn
+}
+
+// IsContainedBy tests whether urn refers to the same or a part of the text that other refers to.
+// Work components left empty in other match any value in urn, so
+// urn:cts:greekLit:tlg0012.tlg001.msA:1.1 is contained by urn:cts:greekLit:tlg0012.tlg001:1.
+// A passage is contained by any passage whose citation levels it starts with,
+// and a passage with a subreference is contained by the same passage without one.
+// As a URN carries no information about document order, a range only contains
+// its own end points and what is contained by them.
+func (urn CTSURN) IsContainedBy(other CTSURN) bool {
+	if urn.InValid || other.InValid {
+		return false
+	}
+	if urn.Namespace != other.Namespace || urn.Textgroup != other.Textgroup {
+		return false
+	}
+	for _, pair := range [][2]string{{urn.WorkPart, other.WorkPart}, {urn.Version, other.Version}, {urn.Exemplar, other.Exemplar}} {
+		if pair[1] != "" && pair[0] != pair[1] {
+			return false
+		}
+	}
+	switch {
+	case other.Passage == "":
+		return true
+	case urn.Passage == "":
+		return false
+	case other.Range && urn.Range:
+		return urn.Start.String() == other.Start.String() && urn.End.String() == other.End.String()
+	case other.Range:
+		return urn.Start.IsContainedBy(other.Start) || urn.Start.IsContainedBy(other.End)
+	case urn.Range:
+		return urn.Start.IsContainedBy(other.Start) && urn.End.IsContainedBy(other.Start)
+	default:
+		return urn.Start.IsContainedBy(other.Start)
+	}
+}
+
+// Contains tests whether other refers to the same or a part of the text that urn refers to.
+// See IsContainedBy.
+func (urn CTSURN) Contains(other CTSURN) bool {
+	return other.IsContainedBy(urn)
+}
+
+// UrnMatch tests whether either of two CTSURNs contains the other,
+// following the "twiddle" (~~) comparison of the CITE architecture
+func (urn CTSURN) UrnMatch(other CTSURN) bool {
+	return urn.IsContainedBy(other) || other.IsContainedBy(urn)
+}
+
+// MatchIgnoringVersion tests whether two CTSURNs match at the notional work level,
+// ignoring version and exemplar, e.g. urn:cts:greekLit:tlg0012.tlg001.msA:1.1
+// and urn:cts:greekLit:tlg0012.tlg001.msB:1
+func (urn CTSURN) MatchIgnoringVersion(other CTSURN) bool {
+	return urn.DropVersion().UrnMatch(other.DropVersion())
+}
+
+// IsPassageAncestorOf tests whether the passage of urn contains the passage of other
+// at a higher citation level, e.g. 1 for 1.1. Work components are not compared
+// and ranges are never ancestors or descendants.
+func (urn CTSURN) IsPassageAncestorOf(other CTSURN) bool {
+	if urn.InValid || other.InValid || urn.Range || other.Range || urn.Passage == "" {
+		return false
+	}
+	return len(urn.Start.Citation) < len(other.Start.Citation) && hasCitationPrefix(other.Start.Citation, urn.Start.Citation)
+}
+
+// IsPassageDescendantOf tests whether the passage of urn is contained
+// by the passage of other at a lower citation level, e.g. 1.1 for 1. See IsPassageAncestorOf.
+func (urn CTSURN) IsPassageDescendantOf(other CTSURN) bool {
+	return other.IsPassageAncestorOf(urn)
+}
+
+// IsContainedBy tests whether node refers to the same or a part of the passage other refers to
+func (node PassageNode) IsContainedBy(other PassageNode) bool {
+	if !hasCitationPrefix(node.Citation, other.Citation) {
+		return false
+	}
+	if !other.Subref.Exists {
+		return true
+	}
+	return len(node.Citation) == len(other.Citation) && node.Subref.Equal(other.Subref)
+}
+
+// Equal tests whether two Subreferences point to the same occurrence of the same token,
+// treating a missing index as [1]
+func (subref Subreference) Equal(other Subreference) bool {
+	if subref.Exists != other.Exists || subref.Token != other.Token {
+		return false
+	}
+	return subref.Occurrence() == other.Occurrence()
+}
+
+// Occurrence returns which occurrence of the token a Subreference points to,
+// which is 1 if no explicit index was given
+func (subref Subreference) Occurrence() int {
+	if subref.Index == 0 {
+		return 1
+	}
+	return subref.Index
+}
+
+// hasCitationPrefix tests whether the citation levels of citation start with those of prefix
+func hasCitationPrefix(citation, prefix []string) bool {
+	if len(prefix) > len(citation) {
+		return false
+	}
+	for i := range prefix {
+		if citation[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
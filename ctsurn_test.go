@@ -0,0 +1,144 @@
+package gocite_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+type parseCTSTestpair struct {
+	input     string
+	component string
+	offset    int
+}
+
+var parseCTSTests = []parseCTSTestpair{
+	{input: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1"},
+	{input: "urn:cts:greekLit:tlg0012.tlg001.msA:"},
+	{input: "urn:cts:greekLit:tlg0012:"},
+	{input: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1@μῆνιν[12]-1.2@ἄειδε"},
+	{input: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1@μῆνιν"},
+	{input: "urn:cts:::", component: "namespace", offset: 8},
+	{input: "urn:cts:greekLit", component: "urn", offset: 16},
+	{input: "urn:cts::tlg0012:1", component: "namespace", offset: 8},
+	{input: "urn:cts:greekLit::1", component: "work", offset: 17},
+	{input: "urn:cts:greekLit:tlg0012..msA:1", component: "work", offset: 25},
+	{input: "urn:cts:greekLit:a.b.c.d.e:1", component: "work", offset: 24},
+	{input: "urn:cite2:greekLit:tlg0012.tlg001:1", component: "protocol", offset: 4},
+	{input: "urx:cts:greekLit:tlg0012.tlg001:1", component: "base", offset: 0},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:1-2-3", component: "passage", offset: 35},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:1-", component: "passage", offset: 34},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:1..2", component: "passage", offset: 34},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:1:2", component: "passage", offset: 33},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:@is", component: "passage", offset: 32},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:1@@", component: "subreference", offset: 34},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:1@", component: "subreference", offset: 34},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:1@is[2", component: "subreference", offset: 36},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:1@is]", component: "subreference", offset: 36},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:1@is[0]", component: "subreference", offset: 37},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:1@is[a]", component: "subreference", offset: 37},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:1@is[2]x", component: "subreference", offset: 39},
+	{input: "urn:cts:greekLit:tlg0012.tlg001:1@[2]", component: "subreference", offset: 34},
+}
+
+func TestParseCTSURN(t *testing.T) {
+	for _, pair := range parseCTSTests {
+		urn, err := gocite.ParseCTSURN(pair.input)
+		if pair.component == "" {
+			if err != nil {
+				t.Error("For", pair.input, "expected no error, got", err)
+				continue
+			}
+			if urn.InValid || urn.String() != pair.input {
+				t.Error("For", pair.input, "expected a valid urn, got", urn)
+			}
+			continue
+		}
+		urnErr, ok := err.(*gocite.URNError)
+		if !ok {
+			t.Error("For", pair.input, "expected *URNError, got", err)
+			continue
+		}
+		if urnErr.Component != pair.component || urnErr.Offset != pair.offset {
+			t.Error(
+				"For", pair.input,
+				"expected", pair.component, pair.offset,
+				"got", urnErr.Component, urnErr.Offset, urnErr.Message,
+			)
+		}
+		if !urn.InValid {
+			t.Error("For", pair.input, "expected urn to be marked InValid")
+		}
+	}
+}
+
+type containmentTestpair struct {
+	urn, other             string
+	containedBy, urnMatch  bool
+	ignoringVersion        bool
+	ancestorOf, descendant bool
+}
+
+var containmentTests = []containmentTestpair{
+	{urn: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1", other: "urn:cts:greekLit:tlg0012.tlg001:1",
+		containedBy: true, urnMatch: true, ignoringVersion: true, descendant: true},
+	{urn: "urn:cts:greekLit:tlg0012.tlg001:1.1", other: "urn:cts:greekLit:tlg0012.tlg001.msA:1",
+		containedBy: false, urnMatch: false, ignoringVersion: true, descendant: true},
+	{urn: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1", other: "urn:cts:greekLit:tlg0012.tlg001.msB:1.1",
+		containedBy: false, urnMatch: false, ignoringVersion: true},
+	{urn: "urn:cts:greekLit:tlg0012.tlg001.msA:1", other: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1",
+		containedBy: false, urnMatch: true, ignoringVersion: true, ancestorOf: true},
+	{urn: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1@μῆνιν", other: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1",
+		containedBy: true, urnMatch: true, ignoringVersion: true},
+	{urn: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1@μῆνιν[1]", other: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1@μῆνιν",
+		containedBy: true, urnMatch: true, ignoringVersion: true},
+	{urn: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1@μῆνιν[2]", other: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1@μῆνιν",
+		containedBy: false, urnMatch: false, ignoringVersion: false},
+	{urn: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1-1.5", other: "urn:cts:greekLit:tlg0012.tlg001.msA:1",
+		containedBy: true, urnMatch: true, ignoringVersion: true},
+	{urn: "urn:cts:greekLit:tlg0012.tlg001.msA:1.10", other: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1",
+		containedBy: false, urnMatch: false, ignoringVersion: false},
+	{urn: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1", other: "urn:cts:greekLit:tlg0012.tlg002.msA:1.1",
+		containedBy: false, urnMatch: false, ignoringVersion: false},
+	{urn: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1", other: "urn:cts:greekLit:tlg0012:",
+		containedBy: true, urnMatch: true, ignoringVersion: true},
+}
+
+func TestCTSURNContainment(t *testing.T) {
+	for _, pair := range containmentTests {
+		urn, other := gocite.SplitCTS(pair.urn), gocite.SplitCTS(pair.other)
+		results := []bool{urn.IsContainedBy(other), other.Contains(urn), urn.UrnMatch(other), other.UrnMatch(urn),
+			urn.MatchIgnoringVersion(other), urn.IsPassageAncestorOf(other), urn.IsPassageDescendantOf(other)}
+		expected := []bool{pair.containedBy, pair.containedBy, pair.urnMatch, pair.urnMatch,
+			pair.ignoringVersion, pair.ancestorOf, pair.descendant}
+		if !reflect.DeepEqual(results, expected) {
+			t.Error(
+				"For", pair.urn, "and", pair.other,
+				"expected", expected,
+				"got", results,
+			)
+		}
+	}
+}
+
+func TestCTSURNDrop(t *testing.T) {
+	urn := gocite.SplitCTS("urn:cts:greekLit:tlg0012.tlg001.msA.tokens:1.1@μῆνιν[1]-1.2@ἄειδε")
+	drops := []struct {
+		output   gocite.CTSURN
+		expected string
+	}{
+		{urn.DropPassage(), "urn:cts:greekLit:tlg0012.tlg001.msA.tokens:"},
+		{urn.DropVersion(), "urn:cts:greekLit:tlg0012.tlg001:1.1@μῆνιν[1]-1.2@ἄειδε"},
+		{urn.DropExemplar(), "urn:cts:greekLit:tlg0012.tlg001.msA:1.1@μῆνιν[1]-1.2@ἄειδε"},
+		{urn.DropSubref(), "urn:cts:greekLit:tlg0012.tlg001.msA.tokens:1.1-1.2"},
+	}
+	for _, drop := range drops {
+		if !reflect.DeepEqual(drop.output, gocite.SplitCTS(drop.expected)) {
+			t.Error(
+				"expected", gocite.SplitCTS(drop.expected),
+				"got", drop.output,
+			)
+		}
+	}
+}
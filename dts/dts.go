@@ -0,0 +1,457 @@
+// Package dts serves the texts of a gocite.TextRepository following the
+// Distributed Text Services (DTS) API. Textgroups become DTS collections,
+// Works become DTS resources and the citation levels of their CTS passages,
+// e.g. book and line, become their citation trees.
+package dts
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ThomasK81/gocite"
+)
+
+// RootID is the id of the collection containing all Textgroups
+const RootID = "default"
+
+// Context is the JSON-LD context of all responses
+var Context = map[string]string{
+	"@vocab": "https://www.w3.org/ns/hydra/core#",
+	"dc":     "http://purl.org/dc/terms/",
+	"dts":    "https://w3id.org/dts/api#",
+}
+
+// Handler is an http.Handler answering the DTS Entry, Collections, Navigation and Document endpoints.
+// BasePath is the path the Handler is mounted at, e.g. "/api/dts", and is used for all links and routing.
+// Its TextRepository is fixed by NewHandler, which builds the citation tree of every Work once.
+// Create a new Handler to serve changed texts.
+type Handler struct {
+	repository gocite.TextRepository
+	BasePath   string
+	Title      string
+	refs       map[string][]Reference
+}
+
+// NewHandler returns a Handler serving the texts of repo at basePath.
+// The citation tree of every Work is built once for the Collections and Navigation endpoints.
+func NewHandler(repo gocite.TextRepository, basePath string) *Handler {
+	h := &Handler{repository: repo, BasePath: strings.TrimSuffix(basePath, "/"), Title: "Texts", refs: map[string][]Reference{}}
+	for _, group := range repo.Textgroups {
+		for _, work := range group.Works {
+			h.refs[work.WorkID] = h.buildReferences(work)
+		}
+	}
+	return h
+}
+
+// EntryPoint is the response of the Entry endpoint
+type EntryPoint struct {
+	Context     string `json:"@context"`
+	ID          string `json:"@id"`
+	Type        string `json:"@type"`
+	Collections string `json:"collections"`
+	Documents   string `json:"documents"`
+	Navigation  string `json:"navigation"`
+}
+
+// Collection is the response of the Collections endpoint and describes its members.
+// Textgroups are of @type Collection, Works are of @type Resource.
+type Collection struct {
+	Context       map[string]string `json:"@context,omitempty"`
+	ID            string            `json:"@id"`
+	Type          string            `json:"@type"`
+	Title         string            `json:"title"`
+	Description   string            `json:"description,omitempty"`
+	TotalItems    int               `json:"totalItems"`
+	TotalParents  int               `json:"dts:totalParents"`
+	TotalChildren int               `json:"dts:totalChildren"`
+	DublinCore    map[string]string `json:"dts:dublincore,omitempty"`
+	CiteDepth     int               `json:"dts:citeDepth,omitempty"`
+	CiteStructure []CiteStructure   `json:"dts:citeStructure,omitempty"`
+	Passage       string            `json:"dts:passage,omitempty"`
+	References    string            `json:"dts:references,omitempty"`
+	Members       []Collection      `json:"member,omitempty"`
+}
+
+// CiteStructure describes a level of the citation tree of a Work, e.g. "book" above "line"
+type CiteStructure struct {
+	CiteType      string          `json:"dts:citeType"`
+	CiteStructure []CiteStructure `json:"dts:citeStructure,omitempty"`
+}
+
+// Navigation is the response of the Navigation endpoint
+type Navigation struct {
+	Context   map[string]string `json:"@context"`
+	ID        string            `json:"@id"`
+	CiteDepth int               `json:"dts:citeDepth"`
+	Level     int               `json:"dts:level"`
+	Passage   string            `json:"dts:passage"`
+	Parent    *string           `json:"dts:parent"`
+	Members   []Reference       `json:"member"`
+}
+
+// Reference is a node of the citation tree of a Work
+type Reference struct {
+	Ref   string `json:"dts:ref"`
+	Level int    `json:"dts:level"`
+}
+
+// Status is the response for all errors
+type Status struct {
+	Context     string `json:"@context"`
+	Type        string `json:"@type"`
+	StatusCode  int    `json:"statusCode"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// ServeHTTP routes a request to the DTS endpoints
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	switch strings.TrimPrefix(r.URL.Path, h.BasePath) {
+	case "", "/":
+		writeJSON(w, http.StatusOK, EntryPoint{Context: h.BasePath + "/contexts/EntryPoint.jsonld", ID: h.BasePath + "/",
+			Type: "EntryPoint", Collections: h.BasePath + "/collections", Documents: h.BasePath + "/document",
+			Navigation: h.BasePath + "/navigation"})
+	case "/collections":
+		h.serveCollections(w, query)
+	case "/navigation":
+		h.serveNavigation(w, query)
+	case "/document":
+		h.serveDocument(w, query)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("unknown endpoint "+r.URL.Path))
+	}
+}
+
+// serveCollections answers the Collections endpoint for the root collection,
+// a Textgroup or a Work. With nav=parents the members are the parents of the collection.
+func (h *Handler) serveCollections(w http.ResponseWriter, query url.Values) {
+	id := query.Get("id")
+	if id == "" {
+		id = RootID
+	}
+	coll, found := h.collection(id)
+	if !found {
+		writeError(w, http.StatusNotFound, errors.New("unknown collection "+id))
+		return
+	}
+	switch query.Get("nav") {
+	case "", "children":
+		for _, child := range h.children(id) {
+			member, _ := h.collection(child)
+			coll.Members = append(coll.Members, member)
+		}
+	case "parents":
+		if parent := h.parent(id); parent != "" {
+			member, _ := h.collection(parent)
+			coll.Members = append(coll.Members, member)
+		}
+	default:
+		writeError(w, http.StatusBadRequest, errors.New("nav must be children or parents"))
+		return
+	}
+	coll.Context = Context
+	writeJSON(w, http.StatusOK, coll)
+}
+
+// collection describes the collection with the given id without its members
+func (h *Handler) collection(id string) (Collection, bool) {
+	if id == RootID {
+		return Collection{ID: RootID, Type: "Collection", Title: h.Title,
+			TotalItems: len(h.repository.Textgroups), TotalChildren: len(h.repository.Textgroups)}, true
+	}
+	for _, group := range h.repository.Textgroups {
+		if group.TextgroupID == id {
+			coll := Collection{ID: id, Type: "Collection", Title: id, TotalItems: len(group.Works),
+				TotalParents: 1, TotalChildren: len(group.Works)}
+			for _, work := range group.Works {
+				if entry, found := h.repository.GetCatalogEntry(work.WorkID); found && entry.GroupName != "" {
+					coll.Title = entry.GroupName
+					break
+				}
+			}
+			return coll, true
+		}
+		for _, work := range group.Works {
+			if work.WorkID == id {
+				return h.resource(work), true
+			}
+		}
+	}
+	return Collection{}, false
+}
+
+// resource describes a Work as a DTS resource
+func (h *Handler) resource(work gocite.Work) Collection {
+	coll := Collection{ID: work.WorkID, Type: "Resource", Title: work.WorkID, TotalParents: 1,
+		CiteDepth:  citeDepth(h.references(work)),
+		Passage:    h.BasePath + "/document?id=" + url.QueryEscape(work.WorkID),
+		References: h.BasePath + "/navigation?id=" + url.QueryEscape(work.WorkID)}
+	entry, found := h.repository.GetCatalogEntry(work.WorkID)
+	if !found {
+		return coll
+	}
+	if entry.WorkTitle != "" {
+		coll.Title = entry.WorkTitle
+	}
+	coll.Description = entry.VersionLabel
+	if entry.Language != "" {
+		coll.DublinCore = map[string]string{"dc:language": entry.Language}
+	}
+	if entry.CitationScheme != "" {
+		types := strings.Split(entry.CitationScheme, ",")
+		for i := len(types) - 1; i >= 0; i-- {
+			coll.CiteStructure = []CiteStructure{{CiteType: strings.TrimSpace(types[i]), CiteStructure: coll.CiteStructure}}
+		}
+	}
+	return coll
+}
+
+// children returns the ids of the members of a collection
+func (h *Handler) children(id string) []string {
+	children := []string{}
+	for _, group := range h.repository.Textgroups {
+		switch id {
+		case RootID:
+			children = append(children, group.TextgroupID)
+		case group.TextgroupID:
+			for _, work := range group.Works {
+				children = append(children, work.WorkID)
+			}
+		}
+	}
+	return children
+}
+
+// parent returns the id of the collection containing the collection with the given id
+func (h *Handler) parent(id string) string {
+	for _, group := range h.repository.Textgroups {
+		if group.TextgroupID == id {
+			return RootID
+		}
+		for _, work := range group.Works {
+			if work.WorkID == id {
+				return group.TextgroupID
+			}
+		}
+	}
+	return ""
+}
+
+// serveNavigation answers the Navigation endpoint with the citation nodes
+// below ref, between start and end or at the top of the citation tree.
+// down is the number of levels returned and defaults to 1 for ref and the top of the tree,
+// and to 0 for start and end, which returns the nodes between them. down=-1 returns all levels.
+func (h *Handler) serveNavigation(w http.ResponseWriter, query url.Values) {
+	work, status, err := h.work(query)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	ref, start, end := query.Get("ref"), query.Get("start"), query.Get("end")
+	if ref != "" && (start != "" || end != "") || (start == "") != (end == "") {
+		writeError(w, http.StatusBadRequest, errors.New("use either ref or both start and end"))
+		return
+	}
+	down := 1
+	if start != "" {
+		down = 0
+	}
+	if query.Get("down") != "" {
+		down, err = strconv.Atoi(query.Get("down"))
+		if err != nil || down < -1 {
+			writeError(w, http.StatusBadRequest, errors.New("invalid down "+strconv.Quote(query.Get("down"))))
+			return
+		}
+	}
+	refs := h.references(work)
+	nav := Navigation{Context: Context, ID: h.BasePath + "/navigation?" + query.Encode(), CiteDepth: citeDepth(refs),
+		Passage: h.BasePath + "/document?id=" + url.QueryEscape(work.WorkID) + "{&ref}{&start}{&end}", Members: []Reference{}}
+	from, to, level := 0, len(refs)-1, 0
+	if ref != "" {
+		from = indexOfRef(refs, ref)
+		if from == -1 {
+			writeError(w, http.StatusNotFound, errors.New("unknown ref "+ref))
+			return
+		}
+		to = lastDescendant(refs, from)
+		level = refs[from].Level
+		nav.Parent = &ref
+	}
+	if start != "" {
+		from, to = indexOfRef(refs, start), indexOfRef(refs, end)
+		if from == -1 || to == -1 || to < from || refs[from].Level != refs[to].Level {
+			writeError(w, http.StatusNotFound, errors.New("invalid range "+start+"-"+end))
+			return
+		}
+		to = lastDescendant(refs, to)
+		level = refs[from].Level - 1
+		if down != -1 {
+			down++
+		}
+	}
+	nav.Level = level + 1
+	for _, r := range refs[from : to+1] {
+		if r.Level > level && (down == -1 || r.Level <= level+down) {
+			nav.Members = append(nav.Members, r)
+		}
+	}
+	writeJSON(w, http.StatusOK, nav)
+}
+
+// serveDocument answers the Document endpoint with the text of a Work, a ref
+// or the nodes from start to end as TEI
+func (h *Handler) serveDocument(w http.ResponseWriter, query url.Values) {
+	work, status, err := h.work(query)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	ref, start, end := query.Get("ref"), query.Get("start"), query.Get("end")
+	if ref != "" && (start != "" || end != "") || (start == "") != (end == "") {
+		writeError(w, http.StatusBadRequest, errors.New("use either ref or both start and end"))
+		return
+	}
+	base := gocite.SplitCTS(work.WorkID).DropPassage().ID
+	var passages []gocite.Passage
+	switch {
+	case ref != "":
+		passages, err = h.repository.GetPassages(base + ref)
+	case start != "":
+		passages, err = h.repository.GetPassages(base + start + "-" + end)
+	default:
+		passages, err = h.repository.GetPassages(work.WorkID)
+	}
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header + `<TEI xmlns="http://www.tei-c.org/ns/1.0">`)
+	if ref == "" && start == "" {
+		buf.WriteString(`<text><body><div type="edition" n="` + escape(work.WorkID) + `">`)
+	} else {
+		buf.WriteString(`<dts:fragment xmlns:dts="https://w3id.org/dts/api#">`)
+	}
+	for _, p := range passages {
+		text, _ := gocite.PassageText(p)
+		buf.WriteString(`<div type="textpart" n="` + escape(gocite.SplitCTS(p.PassageID).Passage) + `">` + escape(text) + `</div>`)
+	}
+	if ref == "" && start == "" {
+		buf.WriteString(`</div></body></text></TEI>`)
+	} else {
+		buf.WriteString(`</dts:fragment></TEI>`)
+	}
+	w.Header().Set("Content-Type", "application/tei+xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, buf.String())
+}
+
+// work returns the Work given in the id parameter along with the status code of an error
+func (h *Handler) work(query url.Values) (gocite.Work, int, error) {
+	id := query.Get("id")
+	if id == "" {
+		return gocite.Work{}, http.StatusBadRequest, errors.New("missing id")
+	}
+	for _, group := range h.repository.Textgroups {
+		for _, work := range group.Works {
+			if work.WorkID == id {
+				return work, http.StatusOK, nil
+			}
+		}
+	}
+	return gocite.Work{}, http.StatusNotFound, errors.New("unknown resource " + id)
+}
+
+// references returns the citation tree of a Work in document order, each node followed by its descendants,
+// e.g. 1, 1.1, 1.2, 2, 2.1 for a Work cited by book and line, as built by NewHandler if there is one
+func (h *Handler) references(work gocite.Work) []Reference {
+	if refs, found := h.refs[work.WorkID]; found {
+		return refs
+	}
+	return h.buildReferences(work)
+}
+
+// buildReferences builds the citation tree of a Work, see references
+func (h *Handler) buildReferences(work gocite.Work) []Reference {
+	refs := []Reference{}
+	passages, err := h.repository.GetPassages(work.WorkID)
+	if err != nil {
+		return refs
+	}
+	last := []string{}
+	for _, p := range passages {
+		citation := gocite.SplitCTS(p.PassageID).Start.Citation
+		for level := 1; level <= len(citation); level++ {
+			ref := strings.Join(citation[:level], ".")
+			if level == len(citation) {
+				ref = gocite.SplitCTS(p.PassageID).Passage
+			}
+			if len(last) >= level && last[level-1] == ref {
+				continue
+			}
+			last = append(last[:level-1], ref)
+			refs = append(refs, Reference{Ref: ref, Level: level})
+		}
+	}
+	return refs
+}
+
+// citeDepth returns the deepest level of a citation tree
+func citeDepth(refs []Reference) int {
+	depth := 0
+	for _, r := range refs {
+		if r.Level > depth {
+			depth = r.Level
+		}
+	}
+	return depth
+}
+
+func indexOfRef(refs []Reference, ref string) int {
+	for i := range refs {
+		if refs[i].Ref == ref {
+			return i
+		}
+	}
+	return -1
+}
+
+// lastDescendant returns the index of the last descendant of the node at index i
+func lastDescendant(refs []Reference, i int) int {
+	j := i
+	for j+1 < len(refs) && refs[j+1].Level > refs[i].Level {
+		j++
+	}
+	return j
+}
+
+func escape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, Status{Context: "http://www.w3.org/ns/hydra/context.jsonld", Type: "Status",
+		StatusCode: status, Title: http.StatusText(status), Description: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/ld+json; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "%s\n", body)
+}
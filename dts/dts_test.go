@@ -0,0 +1,162 @@
+package dts_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+	"github.com/ThomasK81/gocite/dts"
+)
+
+var testCEX = `#!ctscatalog
+urn#citationScheme#groupName#workTitle#versionLabel#exemplarLabel#online#lang
+urn:cts:greekLit:tlg0012.tlg001.msA:#book,line#Homeric epic#Iliad#HMT project diplomatic edition##true#grc
+
+#!ctsdata
+urn:cts:greekLit:tlg0012.tlg001.msA:1.1#Μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος
+urn:cts:greekLit:tlg0012.tlg001.msA:1.2#οὐλομένην, ἣ μυρί' Ἀχαιοῖς ἄλγε' ἔθηκε,
+urn:cts:greekLit:tlg0012.tlg001.msA:1.3#πολλὰς δ' ἰφθίμους ψυχὰς Ἄϊδι προΐαψεν
+urn:cts:greekLit:tlg0012.tlg001.msA:2.1#Ἄλλοι μέν ῥα θεοί τε καὶ ἀνέρες ἱπποκορυσταὶ
+urn:cts:greekLit:tlg0012.tlg001.msA:2.2#εὗδον παννύχιοι, Δία δ' οὐκ ἔχε νήδυμος ὕπνος,
+`
+
+const workID = "urn:cts:greekLit:tlg0012.tlg001.msA:"
+
+func testHandler(t *testing.T) *dts.Handler {
+	data, err := gocite.ReadCEX(strings.NewReader(testCEX))
+	if err != nil {
+		t.Fatal("Error calling ReadCEX: ", err)
+	}
+	return dts.NewHandler(gocite.NewTextRepository(data), "/api/dts")
+}
+
+func get(handler http.Handler, path string, query url.Values) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", path+"?"+query.Encode(), nil))
+	return recorder
+}
+
+func TestCollections(t *testing.T) {
+	handler := testHandler(t)
+	var coll dts.Collection
+	if err := json.Unmarshal(get(handler, "/api/dts/collections", nil).Body.Bytes(), &coll); err != nil {
+		t.Fatal(err)
+	}
+	if coll.ID != dts.RootID || len(coll.Members) != 1 || coll.Members[0].ID != "urn:cts:greekLit:tlg0012:" || coll.Members[0].Title != "Homeric epic" {
+		t.Error("unexpected root collection", coll)
+	}
+	coll = dts.Collection{}
+	if err := json.Unmarshal(get(handler, "/api/dts/collections", url.Values{"id": {"urn:cts:greekLit:tlg0012:"}}).Body.Bytes(), &coll); err != nil {
+		t.Fatal(err)
+	}
+	if len(coll.Members) != 1 || coll.Members[0].Type != "Resource" || coll.Members[0].Title != "Iliad" || coll.Members[0].CiteDepth != 2 {
+		t.Error("unexpected textgroup collection", coll)
+	}
+	expected := []dts.CiteStructure{{CiteType: "book", CiteStructure: []dts.CiteStructure{{CiteType: "line"}}}}
+	if !reflect.DeepEqual(coll.Members[0].CiteStructure, expected) {
+		t.Error("expected", expected, "got", coll.Members[0].CiteStructure)
+	}
+	coll = dts.Collection{}
+	if err := json.Unmarshal(get(handler, "/api/dts/collections", url.Values{"id": {workID}, "nav": {"parents"}}).Body.Bytes(), &coll); err != nil {
+		t.Fatal(err)
+	}
+	if len(coll.Members) != 1 || coll.Members[0].ID != "urn:cts:greekLit:tlg0012:" {
+		t.Error("unexpected parents", coll.Members)
+	}
+}
+
+func TestNavigation(t *testing.T) {
+	handler := testHandler(t)
+	tests := []struct {
+		query    url.Values
+		expected []string
+	}{
+		{query: url.Values{"id": {workID}}, expected: []string{"1", "2"}},
+		{query: url.Values{"id": {workID}, "ref": {"1"}}, expected: []string{"1.1", "1.2", "1.3"}},
+		{query: url.Values{"id": {workID}, "down": {"-1"}}, expected: []string{"1", "1.1", "1.2", "1.3", "2", "2.1", "2.2"}},
+		{query: url.Values{"id": {workID}, "start": {"1.2"}, "end": {"2.1"}}, expected: []string{"1.2", "1.3", "2.1"}},
+		{query: url.Values{"id": {workID}, "start": {"1"}, "end": {"2"}, "down": {"1"}}, expected: []string{"1", "1.1", "1.2", "1.3", "2", "2.1", "2.2"}},
+	}
+	for _, test := range tests {
+		var nav dts.Navigation
+		if err := json.Unmarshal(get(handler, "/api/dts/navigation", test.query).Body.Bytes(), &nav); err != nil {
+			t.Fatal(err)
+		}
+		refs := []string{}
+		for _, member := range nav.Members {
+			refs = append(refs, member.Ref)
+		}
+		if !reflect.DeepEqual(refs, test.expected) || nav.CiteDepth != 2 {
+			t.Error("For", test.query, "expected", test.expected, "got", refs, nav.CiteDepth)
+		}
+	}
+}
+
+func TestDocument(t *testing.T) {
+	handler := testHandler(t)
+	tests := []struct {
+		query    url.Values
+		expected []string
+	}{
+		{query: url.Values{"id": {workID}, "ref": {"2"}}, expected: []string{"2.1", "2.2"}},
+		{query: url.Values{"id": {workID}, "start": {"1.3"}, "end": {"2"}}, expected: []string{"1.3", "2.1", "2.2"}},
+		{query: url.Values{"id": {workID}}, expected: []string{"1.1", "1.2", "1.3", "2.1", "2.2"}},
+	}
+	for _, test := range tests {
+		recorder := get(handler, "/api/dts/document", test.query)
+		var doc struct {
+			Divs []struct {
+				N string `xml:"n,attr"`
+			} `xml:"fragment>div"`
+			Body []struct {
+				N string `xml:"n,attr"`
+			} `xml:"text>body>div>div"`
+		}
+		if err := xml.Unmarshal(recorder.Body.Bytes(), &doc); err != nil {
+			t.Fatal(err, recorder.Body.String())
+		}
+		refs := []string{}
+		for _, div := range append(doc.Divs, doc.Body...) {
+			refs = append(refs, div.N)
+		}
+		if !reflect.DeepEqual(refs, test.expected) {
+			t.Error("For", test.query, "expected", test.expected, "got", refs, recorder.Body.String())
+		}
+	}
+	if body := get(handler, "/api/dts/document", url.Values{"id": {workID}, "ref": {"1.1"}}).Body.String(); !strings.Contains(body, ">Μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος</div>") {
+		t.Error("unexpected document", body)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	handler := testHandler(t)
+	tests := []struct {
+		path   string
+		query  url.Values
+		status int
+	}{
+		{path: "/api/dts/collections", query: url.Values{"id": {"urn:cts:latinLit:phi0959:"}}, status: http.StatusNotFound},
+		{path: "/api/dts/navigation", query: url.Values{}, status: http.StatusBadRequest},
+		{path: "/api/dts/navigation", query: url.Values{"id": {workID}, "ref": {"3"}}, status: http.StatusNotFound},
+		{path: "/api/dts/navigation", query: url.Values{"id": {workID}, "start": {"1"}}, status: http.StatusBadRequest},
+		{path: "/api/dts/navigation", query: url.Values{"id": {workID}, "down": {"x"}}, status: http.StatusBadRequest},
+		{path: "/api/dts/document", query: url.Values{"id": {workID}, "ref": {"3"}}, status: http.StatusNotFound},
+		{path: "/api/dts/search", query: url.Values{}, status: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		recorder := get(handler, test.path, test.query)
+		var status dts.Status
+		if err := json.Unmarshal(recorder.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+		if recorder.Code != test.status || status.StatusCode != test.status {
+			t.Error("For", test.path, test.query, "expected", test.status, "got", recorder.Code, status)
+		}
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/ThomasK81/gocite
 
-go 1.14
+go 1.21
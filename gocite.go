@@ -1,11 +1,17 @@
+// Package gocite reads, navigates and edits texts cited by CTS URNs and objects cited by CITE2 URNs,
+// e.g. from CEX files. A Work keeps its Passages in a flat slice and links them in document order by Prev and Next.
+//
+// Functions editing a Work, like InsertPassage or DelPassage, return a new Work and never change the one they
+// are given. The versions share the ID index and the Analysis and ImageLinks of their Passages, but not
+// the Passages slice: each edit copies it, in time and memory growing with the number of Passages,
+// so an undo history of k versions of a Work holds k Passages slices. Sharing the Passages themselves
+// between versions would take a storage other than the exported slice.
 package gocite
 
 import (
 	"errors"
-	"log"
-	"regexp"
-	"strconv"
-	"strings"
+	"log/slog"
+	"sort"
 )
 
 // CiteVerb implemented similar to https://github.com/cite-architecture/cite-verbs/blob/master/cite-collection/cite-verbs-data-draft.csv
@@ -14,18 +20,56 @@ type CiteVerb struct {
 }
 
 // Cite2Urn implemented as outlined here http://cite-architecture.org/cite2urn/
+// Collection holds the complete collection component (e.g. "msA.v1.sequence"), which is
+// also split into its CollectionID, Version and Property.
+// Object holds the complete object component, which is also parsed
+// into Start and (for ranges) End.
 type Cite2Urn struct {
 	ID, Base, Protocol, Namespace, Collection, Object string
+	CollectionID, Version, Property                   string
+	Start, End                                        ObjectNode
+	Range                                             bool
 	InValid                                           bool
 }
 
+// ObjectNode is a single object reference within a Cite2Urn,
+// that is the whole object selector or one end of a range.
+// Extension holds an extended reference such as a region of interest
+// on an image ("0.1,0.2,0.3,0.4"), without the leading @.
+type ObjectNode struct {
+	ID, Object, Extension string
+}
+
 // CTSURN references text as explained in http://cite-architecture.org/ctsurn/
 // For a note on CTS citations see http://cite-architecture.github.io/cts_spec/
+// Work holds the complete work component (e.g. "tlg0012.tlg001.msA"), which is
+// also split into its Textgroup, WorkPart, Version and Exemplar.
+// Passage holds the complete passage component, which is also parsed
+// into Start and (for ranges) End.
 type CTSURN struct {
 	ID, Base, Protocol, Namespace, Work, Passage string
+	Textgroup, WorkPart, Version, Exemplar       string
+	Start, End                                   PassageNode
+	Range                                        bool
 	InValid                                      bool
 }
 
+// PassageNode is a single passage reference within a CTSURN,
+// that is the whole passage component or one end of a range
+type PassageNode struct {
+	ID       string
+	Citation []string
+	Subref   Subreference
+}
+
+// Subreference points to the Index-th occurrence of Token within a passage.
+// Index is 0 if no explicit [n] was given, which means the first occurrence
+type Subreference struct {
+	Exists bool
+	Token  string
+	Index  int
+}
+
 // Triple is a Simple LinkedData-Triple implementation
 type Triple struct {
 	Subject, Verb, Object string
@@ -37,12 +81,18 @@ type Textgroup struct {
 	Works       []Work
 }
 
-// Work is a container for CTS passages that belong to the same work
+// Work is a container for CTS passages that belong to the same work.
+// CitationScheme names the citation levels of its passages, e.g. "book" and "line".
+// The functions of this package keep an index from PassageIDs to slice indices
+// and an index of the citation nodes the Passages belong to up to date, see BuildIDIndex.
 type Work struct {
-	WorkID      string
-	Passages    []Passage
-	Ordered     bool
-	First, Last PassLoc
+	WorkID         string
+	Passages       []Passage
+	Ordered        bool
+	First, Last    PassLoc
+	CitationScheme []string
+	idIndex        *passageIndex
+	citations      *citationIndex
 }
 
 // Passage is the smallest CTSNode
@@ -86,142 +136,106 @@ type ArrayToken struct {
 }
 
 // SplitCTS splits a CTS URN string in its stem and the passage reference
-//and returns it as a CTSURN
+//and returns it as a CTSURN, which is marked as InValid if the string is not a valid CTS URN.
+//Use ParseCTSURN to learn why a string is not valid.
 func SplitCTS(URNString string) CTSURN {
-	if !IsCTSURN(URNString) {
-		return CTSURN{ID: URNString, InValid: true}
-	}
-	comps := strings.Split(URNString, ":")
-	return CTSURN{ID: URNString,
-		Base:      comps[0],
-		Protocol:  comps[1],
-		Namespace: comps[2],
-		Work:      comps[3],
-		Passage:   comps[4],
-		InValid:   false}
+	urn, _ := ParseCTSURN(URNString)
+	return urn
 }
 
 // SplitCITE splits a Cite URN string in its stem and the passage reference
-//and returns it as a Cite2Urn
+//and returns it as a Cite2Urn, which is marked as InValid if the string is not a valid CITE2 URN.
+//Use ParseCITEURN to learn why a string is not valid.
 func SplitCITE(URNString string) Cite2Urn {
-	if !IsCITEURN(URNString) {
-		return Cite2Urn{ID: URNString, InValid: true}
-	}
-	comps := strings.Split(URNString, ":")
-	return Cite2Urn{ID: URNString,
-		Base:       comps[0],
-		Protocol:   comps[1],
-		Namespace:  comps[2],
-		Collection: comps[3],
-		Object:     comps[4],
-		InValid:    false}
+	urn, _ := ParseCITEURN(URNString)
+	return urn
 }
 
 // IsRange returns a boolean whether a CTS URN string is a range
 func IsRange(URNString string) bool {
-	switch {
-	case len(strings.Split(URNString, ":")) < 5:
-		return false
-	case strings.Contains(strings.Split(URNString, ":")[4], "-"):
-		return true
-	default:
-		return false
-	}
+	urn := SplitCTS(URNString)
+	return !urn.InValid && urn.Range
 }
 
 // WantSubstr tests whether the passage part of a URN string refers to a substring
 func WantSubstr(URNString string) bool {
-	return strings.Contains(URNString, "@")
+	urn := SplitCTS(URNString)
+	if urn.InValid {
+		return false
+	}
+	return urn.Start.Subref.Exists || urn.End.Subref.Exists
 }
 
 // IsCTSURN tests whether a string is a valid CTSURN
 func IsCTSURN(URNString string) bool {
-	test := strings.Split(URNString, ":")
-	switch {
-	case len(test) != 5:
-		return false
-	case test[0] != "urn":
-		return false
-	case test[1] != "cts":
-		return false
-	default:
-		return true
-	}
+	_, err := ParseCTSURN(URNString)
+	return err == nil
 }
 
 // IsCITEURN tests whether a string is a valid CITE URN
 func IsCITEURN(URNString string) bool {
-	test := strings.Split(URNString, ":")
-	switch {
-	case len(test) != 5:
-		return false
-	case test[0] != "urn":
-		return false
-	case test[1] != "cite2":
-		return false
-	default:
-		return true
-	}
+	_, err := ParseCITEURN(URNString)
+	return err == nil
 }
 
 // IsTextgroupID tests whether a CTSURN (string) points to the textgroup level
 func IsTextgroupID(URNString string) bool {
-	if !IsCTSURN(URNString) {
-		return false
-	}
-	if len(strings.Split(SplitCTS(URNString).Work, ".")) != 1 {
+	urn := SplitCTS(URNString)
+	if urn.InValid {
 		return false
 	}
-	return true
+	return urn.WorkLevel() == 1
 }
 
 // IsWorkID tests whether a CTSURN (string) points to the work level
 func IsWorkID(URNString string) bool {
-	if !IsCTSURN(URNString) {
-		return false
-	}
-	if len(strings.Split(SplitCTS(URNString).Work, ".")) != 2 {
+	urn := SplitCTS(URNString)
+	if urn.InValid {
 		return false
 	}
-	return true
+	return urn.WorkLevel() == 2
 }
 
 // IsVersionID tests whether a CTSURN (string) points to the version level
 func IsVersionID(URNString string) bool {
-	if !IsCTSURN(URNString) {
-		return false
-	}
-	if len(strings.Split(SplitCTS(URNString).Work, ".")) != 3 {
+	urn := SplitCTS(URNString)
+	if urn.InValid {
 		return false
 	}
-	return true
+	return urn.WorkLevel() == 3
 }
 
 // IsExemplarID tests whether a CTSURN (string) points to the exemplar level
 func IsExemplarID(URNString string) bool {
-	if !IsCTSURN(URNString) {
-		return false
-	}
-	if len(strings.Split(SplitCTS(URNString).Work, ".")) != 4 {
+	urn := SplitCTS(URNString)
+	if urn.InValid {
 		return false
 	}
-	return true
+	return urn.WorkLevel() == 4
 }
 
 // GetPassageByID returns a Passage given the PassageID in a given Work
 func GetPassageByID(passageID string, work Work) (Passage, error) {
-	for i := range work.Passages {
-		if work.Passages[i].PassageID == passageID {
-			return work.Passages[i], nil
-		}
+	if index, found := GetIndexByID(passageID, work); found {
+		return work.Passages[index], nil
 	}
 	return Passage{}, errors.New("couldn't find passage")
 }
 
 // GetIndexByID searches for an ID in a given work and if found,
 // returns its slice index in the Work.Passages slice (not the passage.Index)
-// along with a bool indicating whether it has found the Passage
+// along with a bool indicating whether it has found the Passage.
+// Passages of Works built or changed by this package are found, or found missing, in constant time
+// using their ID index. Works without an index for their Passages slice are searched linearly,
+// as are IDs the index has at the wrong slice index, as Passages may have been changed by hand (see BuildIDIndex).
 func GetIndexByID(passageID string, work Work) (int, bool) {
+	index, found := work.idIndex.lookup(passageID)
+	if found && index >= 0 && index < len(work.Passages) && work.Passages[index].PassageID == passageID {
+		return index, true
+	}
+	if !found && work.idIndex.covers(work.Passages) {
+		return 0, false
+	}
 	for index := range work.Passages {
 		if work.Passages[index].PassageID == passageID {
 			return index, true
@@ -230,6 +244,15 @@ func GetIndexByID(passageID string, work Work) (int, bool) {
 	return 0, false
 }
 
+// BuildIDIndex returns the Work with a freshly built index of its PassageIDs,
+// which makes GetIndexByID, GetPassageByID, GetNext and GetPrev run in constant time,
+// and of the citation nodes containing them, which GetLeafPassages and ranges of containers use.
+// It is only needed for Works that were assembled or changed outside of this package.
+func BuildIDIndex(work Work) Work {
+	work.idIndex = newPassageIndex(work.Passages)
+	return buildCitationIndex(work)
+}
+
 // GetPassageByInd returns the Passage at given Index in the Work.Passages slice
 //(Not by Passage.Index)
 func GetPassageByInd(sliceIndex int, work Work) (Passage, error) {
@@ -254,10 +277,8 @@ func GetLast(work Work) Passage {
 // GetNext returns the Passage after the Passage given the PassageID in given Work
 //(not the next in the work.Passages slice)
 func GetNext(passageID string, work Work) Passage {
-	for i := range work.Passages {
-		if work.Passages[i].PassageID == passageID {
-			return work.Passages[work.Passages[i].Next.Index]
-		}
+	if i, found := GetIndexByID(passageID, work); found {
+		return work.Passages[work.Passages[i].Next.Index]
 	}
 	return Passage{}
 }
@@ -265,16 +286,17 @@ func GetNext(passageID string, work Work) Passage {
 // GetPrev returns the Passage previous to the given the PassageID in given a Work
 //(not the next in the work.Passages slice)
 func GetPrev(passageID string, work Work) Passage {
-	for i := range work.Passages {
-		if work.Passages[i].PassageID == passageID {
-			return work.Passages[work.Passages[i].Prev.Index]
-		}
+	if i, found := GetIndexByID(passageID, work); found {
+		return work.Passages[work.Passages[i].Prev.Index]
 	}
 	return Passage{}
 }
 
-// DelPassage deletes a Passage from a Work by changing the references
-func DelPassage(passageID string, work Work) (Work, error) {
+// DelPassage deletes a Passage from a Work, linking its Prev and Next to each other.
+// The Passages slice is compacted, so that the indices in all PassLocs, the Passage.Index values
+// and the ID index are rewritten. An ordered Work stays ordered.
+// The deletion can be logged, see WithLogger.
+func DelPassage(passageID string, work Work, opts ...Option) (Work, error) {
 	if len(work.Passages) == 0 {
 		return work, errors.New("DelPassage: Work was empty")
 	}
@@ -282,67 +304,118 @@ func DelPassage(passageID string, work Work) (Work, error) {
 	if !found {
 		return work, errors.New("DelPassage: Passage " + passageID + " not found in Work " + work.WorkID)
 	}
-	passage, err := GetPassageByInd(index, work)
-	if err != nil {
-		log.Printf("DelPassage: Error getting Passage by id: %s\n", err)
-		return work, err
-	}
-	switch {
-	//passage without Prev and Next: was the last passage remaining in this Work
-	case !work.Passages[index].Prev.Exists && !work.Passages[index].Next.Exists:
-		temp := Work{WorkID: work.WorkID, Ordered: true} //an empty work that has nothing but a WorkID and Ordered field
-		return temp, nil
-		//passage has no Prev: was the first Passage in the Work
-	case !work.Passages[index].Prev.Exists:
-		work, err := DelFirstPassage(work)
-		if err == nil {
-			return work, nil
-		}
-		return work, err
-		//passage has no Next: was the last Passage in the Work
-	case !work.Passages[index].Next.Exists:
-		work, err := DelLastPassage(work)
-		if err == nil {
-			return work, nil
-		}
-		return work, err
-	default:
-		work.Passages[passage.Prev.Index].Next = passage.Next
-		work.Passages[passage.Next.Index].Prev = passage.Prev
-		work.Passages[index] = Passage{}
-		work.Ordered = false
-	}
-	return work, nil
+	return delIndices([]int{index}, newOptions(opts), work), nil
 }
 
-// DelFirstPassage deletes the first Passage from a Work by changing the references
-func DelFirstPassage(work Work) (Work, error) {
+// DelFirstPassage deletes the first Passage from a Work, see DelPassage
+func DelFirstPassage(work Work, opts ...Option) (Work, error) {
 	if len(work.Passages) == 0 {
 		return work, errors.New("DelFirstPassage: Work was empty")
 	}
 	passageIndex, found := GetFirstIndex(work)
-	if !found {
+	if !found || passageIndex < 0 || passageIndex >= len(work.Passages) {
 		return work, errors.New("DelFirstPassage: First Index not found")
 	}
-	work.First = work.Passages[passageIndex].Next
-	work.Passages[work.First.Index].Prev = PassLoc{Exists: false, PassageID: "", Index: 0}
-	work.Passages[passageIndex] = Passage{}
-	return work, nil
+	return delIndices([]int{passageIndex}, newOptions(opts), work), nil
 }
 
-// DelLastPassage deletes the last Passage from a Work by changing the references
-func DelLastPassage(work Work) (Work, error) {
+// DelLastPassage deletes the last Passage from a Work, see DelPassage
+func DelLastPassage(work Work, opts ...Option) (Work, error) {
 	if len(work.Passages) == 0 {
 		return work, errors.New("DelLastPassage: Work was empty")
 	}
 	passageIndex, found := GetLastIndex(work)
-	if !found {
+	if !found || passageIndex < 0 || passageIndex >= len(work.Passages) {
 		return work, errors.New("DelLastPassage: Last Index not found")
 	}
-	work.Last = work.Passages[passageIndex].Prev
-	work.Passages[work.Last.Index].Next = PassLoc{}
-	work.Passages[passageIndex] = Passage{}
-	return work, nil
+	return delIndices([]int{passageIndex}, newOptions(opts), work), nil
+}
+
+// DelRange deletes all Passages a CTS URN refers to from a Work in one go,
+// e.g. the lines 1.5 to 1.20 for urn:cts:greekLit:tlg0012.tlg001.msA:1.5-1.20
+// or all lines of book 1 for urn:cts:greekLit:tlg0012.tlg001.msA:1, see DelPassage.
+// Subreferences are refused, as they refer to parts of Passages.
+func DelRange(ctsID string, work Work, opts ...Option) (Work, error) {
+	urn := SplitCTS(ctsID)
+	if urn.InValid || urn.Passage == "" {
+		return work, errors.New("DelRange: " + ctsID + " does not refer to Passages")
+	}
+	if urn.Start.Subref.Exists || urn.End.Subref.Exists {
+		return work, errors.New("DelRange: cannot delete parts of Passages: " + ctsID)
+	}
+	passages, err := passagesByID(ctsID, work)
+	if err != nil {
+		return work, errors.New("DelRange: " + err.Error())
+	}
+	indices := []int{}
+	for _, p := range passages {
+		index, _ := GetIndexByID(p.PassageID, work)
+		indices = append(indices, index)
+	}
+	return delIndices(indices, newOptions(opts), work), nil
+}
+
+// delIndices unlinks the Passages at the given slice indices one after another
+// and returns the Work with a compacted copy of the Passages slice
+func delIndices(indices []int, o options, work Work) Work {
+	if o.debug() {
+		o.log("deleting Passages", work, passageIDs("passages", indices, work))
+	}
+	work = ownPassages(work)
+	for _, index := range indices {
+		p := work.Passages[index]
+		if p.Prev.Exists && validSlot(p.Prev.Index, work) {
+			work.Passages[p.Prev.Index].Next = p.Next
+		} else {
+			work.First = p.Next
+		}
+		if p.Next.Exists && validSlot(p.Next.Index, work) {
+			work.Passages[p.Next.Index].Prev = p.Prev
+		} else {
+			work.Last = p.Prev
+		}
+	}
+	removed := append([]int{}, indices...)
+	sort.Ints(removed)
+	return compact(removed, work)
+}
+
+// compact removes the Passages at the removed slice indices, given in ascending order, and empty Passages
+// from a Work whose Passages slice is its own, and rewrites the slice indices in all PassLocs,
+// the Passage.Index values and the ID index
+func compact(removed []int, work Work) Work {
+	dropped := make([]int, 0, len(removed))
+	passages := work.Passages[:0]
+	for i, p := range work.Passages {
+		for len(removed) > 0 && removed[0] < i {
+			removed = removed[1:]
+		}
+		if len(removed) > 0 && removed[0] == i || p.PassageID == "" {
+			dropped = append(dropped, i)
+			continue
+		}
+		passages = append(passages, p)
+	}
+	remap := func(loc PassLoc) PassLoc {
+		if !loc.Exists || loc.Index < 0 || loc.Index >= len(work.Passages) {
+			return loc
+		}
+		shift := sort.SearchInts(dropped, loc.Index)
+		if shift < len(dropped) && dropped[shift] == loc.Index {
+			return loc
+		}
+		loc.Index -= shift
+		return loc
+	}
+	for i := range passages {
+		passages[i].Index = i
+		passages[i].Prev = remap(passages[i].Prev)
+		passages[i].Next = remap(passages[i].Next)
+	}
+	work.First, work.Last = remap(work.First), remap(work.Last)
+	work.Passages = passages
+	work.idIndex = work.idIndex.with(nil, dropped, passages)
+	return buildCitationIndex(work)
 }
 
 /*//FindFirstIndex is deprecated and replaced by GetFirstIndex for legacy
@@ -404,57 +477,55 @@ func GetLastIndex(work Work) (int, bool) {
 // SortPassages sorts the Passages in the Work.Passages slice from First to Last
 //according to their Passage.Index values
 //empty Passages are not taken over
-func SortPassages(work Work) (Work, error) {
-	log.Printf("\nSorting Passages in %s\n", work.WorkID)
+//Broken or cyclic links are reported as errors, see ValidateWork and RepairWork
+//The steps of the sorting can be logged, see WithLogger
+func SortPassages(work Work, opts ...Option) (Work, error) {
+	o := newOptions(opts)
+	if o.debug() {
+		o.log("sorting Passages", work, slog.Int("passages", len(work.Passages)), slog.Bool("ordered", work.Ordered))
+	}
 	if len(work.Passages) == 0 {
-		log.Println("Work was empty")
 		return work, errors.New("SortPassages: Work was empty")
 	}
-	if work.Ordered {
-		log.Println("Work is marked as ordered")
-	} else {
-		log.Println("Work is marked as NOT ordered")
-	}
 	var cursor int
 	cursor, found := GetFirstIndex(work) //cursor points to slice index of the next Passage in work which is to be appended to result
 	if !found {                          //if there is not first index saved in any of the Passage.Index fields, work will be returned unsorted
-		log.Println("First Index not saved in Work yet. Trying to find it now")
 		cursor, found = FindFirstIndex(work)
 		if !found {
-			log.Println("First Index not found in Work")
 			return work, errors.New("SortPassages: First Index not found in Work " + work.WorkID) //GetFirstIndex does not actively search for the lowest (and therefore first) index in a work
 		}
 	}
-	log.Printf("First Index: %d, %s\n", cursor, work.Passages[cursor].PassageID)
 	lastIndex, found := GetLastIndex(work) //find the last Index, needed as termination condition
 	if !found {
-		log.Println("Last Index not saved in Work yet. Trying to find it now")
 		lastIndex, found = FindLastIndex(work)
 		if !found {
-			log.Println("Last Index not found in Work")
 			return work, errors.New("SortPassages: Last Index not found in work " + work.WorkID)
 		}
 	}
-	log.Printf("Last Index: %d, %s\n", lastIndex, work.Passages[lastIndex].PassageID) //work.Passages[lastIndex].PassageID) shows the Passage at the last index of the USORTED ARRAY!
-	result := Work{WorkID: work.WorkID, Ordered: true}                                //result is the sorted Work that will be returned
-	index := 0                                                                        //index represents the index the next Passage will get in its Index field
+	if o.debug() {
+		o.log("found First and Last", work, slog.Int("first", cursor), slog.Int("last", lastIndex))
+	}
+	result := Work{WorkID: work.WorkID, Ordered: true, CitationScheme: work.CitationScheme} //result is the sorted Work that will be returned
+	index := 0                                                                              //index represents the index the next Passage will get in its Index field
 	last := false
 	for !last {
+		if !validSlot(cursor, work) {
+			return work, errors.New("SortPassages: Next Index out of bounds in Work " + work.WorkID)
+		}
+		if len(result.Passages) == len(work.Passages) {
+			return work, errors.New("SortPassages: work is loopy: " + work.WorkID)
+		}
 		tempPassage := work.Passages[cursor] //get the Passage from work
-		log.Printf("\ntempPassage: %s\nIndex: %d\nPrev.Index: %d Prev.PassageID: %s\nNext.Index: %d Next.PassageID: %s\n",
-			tempPassage.PassageID, tempPassage.Index,
-			tempPassage.Prev.Index, tempPassage.Prev.PassageID,
-			tempPassage.Next.Index, tempPassage.Next.PassageID)
+		if o.debug() {
+			o.log("moving Passage", work, slog.String("passage", tempPassage.PassageID), slog.Int("from", tempPassage.Index), slog.Int("to", index))
+		}
 		tempPassage.Index = index //set its own Index to the current index
 		result.First.Index = 0    //set the index of First to 0
 		if index != 0 {           //if this is not the first Passage in result
 			tempPassage.Prev.Index = index - 1 //set the Prev index field to one lower than the current index
-		} else {
-			log.Println("*** I'm the first. ***")
 		}
-		if work.Passages[cursor].PassageID == work.Last.PassageID { //if the cursor points to the last index
+		if work.Passages[cursor].PassageID == work.Last.PassageID || !work.Passages[cursor].Next.Exists { //if the cursor points to the last index
 			last = true //mark it in the last variable
-			log.Println("*** I'm the last.  ***")
 		}
 		if last == false { //if this is not the last Passage in the work
 			tempPassage.Next.Index = index + 1 //set the Next index field to one higher than the current index
@@ -467,30 +538,30 @@ func SortPassages(work Work) (Work, error) {
 	result.Passages[0].Prev = PassLoc{}
 	lastIndex, found = FindLastIndex(result)
 	if !found {
-		log.Println("Last Index not found in result")
 		return work, errors.New("SortPassages: Last Index not found in result " + work.WorkID)
 	}
 	result.Last = PassLoc{Exists: true, PassageID: result.Passages[lastIndex].PassageID, Index: lastIndex}
 	result.Passages[lastIndex].Next = PassLoc{}
-	log.Println("*** Sorting finished. ***")
-	log.Println("** Before **")
-	log.Printf("work.First.Index: %d, work.First.PassageID: %s\n", work.First.Index, work.First.PassageID)
-	log.Printf("work.Last.Index: %d, work.Last.PassageID: %s\n", work.Last.Index, work.Last.PassageID)
-	log.Println("** Afterwards **")
-	log.Printf("result.First.Index: %d, result.First.PassageID: %s\n", result.First.Index, result.First.PassageID)
-	log.Printf("result.Last.Index: %d, result.Last.PassageID: %s\n", result.Last.Index, result.Last.PassageID)
-	return result, nil
-}
-
-// InsertPassage inserts a Passage into a Work
-func InsertPassage(passage Passage, work Work) (Work, error) {
+	if o.debug() {
+		o.log("sorted Passages", work, slog.String("first", result.First.PassageID), slog.String("last", result.Last.PassageID))
+	}
+	return BuildIDIndex(result), nil
+}
+
+// InsertPassage inserts a Passage into a Work, see Clone.
+// The insertion can be logged, see WithLogger.
+func InsertPassage(passage Passage, work Work, opts ...Option) (Work, error) {
+	if o := newOptions(opts); o.debug() {
+		o.log("inserting Passage", work, slog.String("passage", passage.PassageID), slog.String("prev", passage.Prev.PassageID), slog.String("next", passage.Next.PassageID))
+	}
+	work = ownPassages(work)
 	if len(work.Passages) == 0 { //if the work has no passages yet
 		work.First = PassLoc{Exists: true, PassageID: passage.PassageID, Index: 0}
 		work.Last = PassLoc{Exists: true, PassageID: passage.PassageID, Index: 0}
 		passage.Next = PassLoc{}
 		passage.Prev = PassLoc{}
 		work.Passages = append(work.Passages, passage)
-		return work, nil
+		return BuildIDIndex(work), nil
 	}
 	nextIndex, nextExists := GetIndexByID(passage.Next.PassageID, work)
 	prevIndex, prevExists := GetIndexByID(passage.Prev.PassageID, work)
@@ -526,95 +597,29 @@ func InsertPassage(passage Passage, work Work) (Work, error) {
 		work.Passages[nextIndex].Prev = passloc
 		work.Passages[len(work.Passages)-1].Index = len(work.Passages) - 1
 	}
+	work.idIndex = work.idIndex.with(map[string]int{passage.PassageID: passloc.Index}, nil, work.Passages)
 	work.Ordered = false
-	return work, nil
+	return buildCitationIndex(work), nil
 }
 
-// RReturnSubStr returns the substring identified by the reverse of @substr[n]. [n] is optional.
+// RReturnSubStr returns the substring of s from its beginning to the end of the word identified by @substr[n].
+// [n] is optional. See FindSubref.
 func RReturnSubStr(cmd, s string) (string, error) {
-	newstr := ""
-	switch strings.Contains(cmd, "[") {
-	case false:
-		add, err := before(s, cmd)
-		if err != nil {
-			return "", err
-		}
-		newstr = add + cmd
-		return newstr, nil
-	case true:
-		cmdSl := strings.Split(cmd, "[")
-		if len(cmdSl) != 2 {
-			return s, errors.New("argument error")
-		}
-		cmdSl[1] = strings.Replace(cmdSl[1], "]", "", 1)
-		n, err := strconv.Atoi(cmdSl[1])
-		if err != nil {
-			return s, err
-		}
-		if strings.Count(s, cmdSl[0]) < n {
-			return s, errors.New("argument error")
-		}
-		strSl := strings.SplitN(s, cmdSl[0], n+1)
-		strSl = strSl[0:n]
-		newstr = strings.Join(strSl, cmdSl[0]) + cmdSl[0]
+	_, end, err := FindSubref(parseSubreference(cmd), s)
+	if err != nil {
+		return "", err
 	}
-	return newstr, nil
+	return string([]rune(s)[:end]), nil
 }
 
-// ReturnSubStr returns the substring identified by @substr[n]. [n] is optional.
+// ReturnSubStr returns the substring of s from the start of the word identified by @substr[n] to its end.
+// [n] is optional. See FindSubref.
 func ReturnSubStr(cmd, s string) (string, error) {
-	newstr := ""
-	switch strings.Contains(cmd, "[") {
-	case false:
-		add, err := after(s, cmd)
-		if err != nil {
-			return "", err
-		}
-		newstr = cmd + add
-		return newstr, nil
-	case true:
-		cmdSl := strings.Split(cmd, "[")
-		if len(cmdSl) != 2 {
-			return s, errors.New("argument error")
-		}
-		cmdSl[1] = strings.Replace(cmdSl[1], "]", "", 1)
-		n, err := strconv.Atoi(cmdSl[1])
-		if err != nil {
-			return s, err
-		}
-		if strings.Count(s, cmdSl[0]) < n {
-			return s, errors.New("argument error")
-		}
-		strSl := strings.SplitN(s, cmdSl[0], n+1)
-		newstr = cmdSl[0] + strSl[n]
-	}
-	return newstr, nil
-}
-
-// before returns the substring of the originalString that antecedes the beforThisString,
-//as long as the beforeThisString is contained in the originalString
-func before(originalString string, beforeThisString string) (string, error) {
-	// Get substring before a string.
-	pos := strings.Index(originalString, beforeThisString)
-	if pos == -1 {
-		return "", errors.New(beforeThisString + "not found")
-	}
-	return originalString[0:pos], nil
-}
-
-// after returns the substring of the originalString that precedes the afterThisString,
-//as long as the afterThisString is contained in the originalString.
-func after(originalString string, afterThisString string) (string, error) {
-	// Get substring after a string.
-	pos := strings.Index(originalString, afterThisString)
-	if pos == -1 {
-		return "", errors.New(afterThisString + " not found in " + originalString)
-	}
-	adjustedPos := pos + len(afterThisString)
-	if adjustedPos >= len(originalString) {
-		return "", nil
+	start, _, err := FindSubref(parseSubreference(cmd), s)
+	if err != nil {
+		return "", err
 	}
-	return originalString[adjustedPos:], nil
+	return string([]rune(s)[start:]), nil
 }
 
 // FindTextTokens finds the analysis that contains txt tokens
@@ -629,186 +634,18 @@ func FindTextTokens(p Passage) (index int, found bool) {
 }
 
 // ExtractTextByID extracts the textual information from a Passage or multiple Passages in a Work
+// A citation node that is not a Passage itself, e.g. a book in a Work cited by book and line,
+// is extracted as all Passages it contains. Range ends may cite different levels:
+// 1.590-2 runs from 1.590 to the last Passage of 2, 1-3 from the first Passage of 1 to the last of 3.
+// Use ExtractSpansByID to learn where in the Passages the extracted text is found.
 func ExtractTextByID(ctsID string, work Work) ([]TextAndID, error) {
-	text := []string{}
-	extrID := []string{}
-	startsub := false
-	endsub := false
-	startcmd := ""
-	endcmd := ""
-	if !IsCTSURN(ctsID) {
-		return []TextAndID{}, errors.New("urn is not a valid cts urn")
-	}
-	switch IsRange(ctsID) {
-	case false:
-		switch WantSubstr(ctsID) {
-		case false:
-			p, err := GetPassageByID(ctsID, work)
-			if err != nil {
-				return []TextAndID{}, err
-			}
-			index, found := FindTextTokens(p)
-			if !found {
-				return []TextAndID{}, errors.New("txt not found")
-			}
-			txt := strings.Join(p.Analysis[index].Array.CharRepres, "")
-			return []TextAndID{{ID: ctsID, Text: txt}}, nil
-		case true:
-			idSl := strings.Split(ctsID, "@")
-			if len(idSl) != 2 {
-				return []TextAndID{}, errors.New("two many @")
-			}
-			p, err := GetPassageByID(idSl[0], work)
-			if err != nil {
-				return []TextAndID{}, err
-			}
-			index, found := FindTextTokens(p)
-			if !found {
-				return []TextAndID{}, errors.New("txt not found")
-			}
-			txt := strings.Join(p.Analysis[index].Array.CharRepres, "")
-			_, err = ReturnSubStr(idSl[1], txt)
-			if err != nil {
-				return []TextAndID{}, err
-			}
-			reg, err := regexp.Compile(`\[\d\]`)
-			if err != nil {
-				return []TextAndID{}, err
-			}
-			idSl[1] = reg.ReplaceAllString(idSl[1], "")
-			return []TextAndID{{ID: ctsID, Text: idSl[1]}}, nil
-		}
-	case true:
-		start, end, err := findStartEnd(ctsID)
-		firstid := start
-		lastid := end
-		startRoot := strings.Split(start, "@")
-		endRoot := strings.Split(end, "@")
-		if startRoot[0] == endRoot[0] {
-			if !WantSubstr(start) || !WantSubstr(end) {
-				return []TextAndID{}, errors.New("substringing in the same line has the format 1@start-1@end")
-			}
-			p, err := GetPassageByID(startRoot[0], work)
-			if err != nil {
-				return []TextAndID{}, err
-			}
-			index, found := FindTextTokens(p)
-			if !found {
-				return []TextAndID{}, errors.New("txt not found")
-			}
-			txt := strings.Join(p.Analysis[index].Array.CharRepres, "")
-			txt, err = ReturnSubStr(startRoot[1], txt)
-			if err != nil {
-				return []TextAndID{}, err
-			}
-			txt, err = RReturnSubStr(endRoot[1], txt)
-			if err != nil {
-				return []TextAndID{}, err
-			}
-			return []TextAndID{{ID: ctsID, Text: txt}}, nil
-		}
-		if err != nil {
-			return []TextAndID{}, err
-		}
-		if WantSubstr(start) {
-			idSl := strings.Split(start, "@")
-			if len(idSl) != 2 {
-				return []TextAndID{}, errors.New("two many @")
-			}
-			startsub = true
-			start = idSl[0]
-			startcmd = idSl[1]
-		}
-		if WantSubstr(end) {
-			idSl2 := strings.Split(end, "@")
-			if len(idSl2) != 2 {
-				return []TextAndID{}, errors.New("two many @")
-			}
-			endsub = true
-			end = idSl2[0]
-			endcmd = idSl2[1]
-		}
-		switch work.Ordered {
-		case true:
-			startindex, found := GetIndexByID(start, work)
-			endindex, found2 := GetIndexByID(end, work)
-			if !found || !found2 {
-				return []TextAndID{}, errors.New("passage not found")
-			}
-			for i := startindex; i < endindex+1; i++ {
-				switch i {
-				case startindex:
-					extrID = append(extrID, firstid)
-				case endindex:
-					extrID = append(extrID, lastid)
-				default:
-					extrID = append(extrID, work.Passages[i].PassageID)
-				}
-				index, _ := FindTextTokens(work.Passages[i])
-
-				txt := strings.Join(work.Passages[i].Analysis[index].Array.CharRepres, "")
-				text = append(text, txt)
-			}
-		case false:
-			_, found := GetIndexByID(start, work)
-			_, found2 := GetIndexByID(end, work)
-			if !found {
-				return []TextAndID{}, errors.New("Index of start passage not found")
-			}
-			if !found2 {
-				return []TextAndID{}, errors.New("Index of end passage not found")
-			}
-			found = false
-			startID := start
-			IDsVisited := []string{}
-			for !found {
-				p, err := GetPassageByID(startID, work)
-				switch startID {
-				case start:
-					extrID = append(extrID, firstid)
-				case end:
-					extrID = append(extrID, lastid)
-				default:
-					extrID = append(extrID, p.PassageID)
-				}
-				if err != nil {
-					return []TextAndID{}, errors.New("passage not found")
-				}
-				startID = p.Next.PassageID
-				if contains(IDsVisited, startID) {
-					return []TextAndID{}, errors.New("work is loopy")
-				}
-				IDsVisited = append(IDsVisited, startID)
-				if p.PassageID == end {
-					found = true
-				}
-				if p.PassageID != end && p.Next.Exists != true {
-					return []TextAndID{}, errors.New("unexpected end of work")
-				}
-				index, found := FindTextTokens(p)
-				if !found {
-					return []TextAndID{}, errors.New("txt not found")
-				}
-				txt := strings.Join(p.Analysis[index].Array.CharRepres, "")
-				text = append(text, txt)
-			}
-		}
-		if startsub {
-			text[0], err = ReturnSubStr(startcmd, text[0])
-			if err != nil {
-				return []TextAndID{}, err
-			}
-		}
-		if endsub {
-			text[len(text)-1], err = RReturnSubStr(endcmd, text[len(text)-1])
-			if err != nil {
-				return []TextAndID{}, err
-			}
-		}
+	spans, err := ExtractSpansByID(ctsID, work)
+	if err != nil {
+		return []TextAndID{}, err
 	}
 	selection := []TextAndID{}
-	for i := range text {
-		selection = append(selection, TextAndID{ID: extrID[i], Text: text[i]})
+	for _, span := range spans {
+		selection = append(selection, TextAndID{ID: span.ID, Text: span.Text})
 	}
 	return selection, nil
 }
@@ -823,17 +660,51 @@ func contains(haystack []string, needle string) bool {
 	return false
 }
 
-func findStartEnd(URNString string) (start, end string, err error) {
-	urn := SplitCTS(URNString)
-	if urn.InValid {
-		return "", "", errors.New("invalid urn")
+// rangeBound returns the PassageID of the Passage a range starts or ends with.
+// This is the Passage node cites or, for a citation node containing Passages at a deeper level,
+// the first Passage it contains for the start of the range and the last one for the end.
+func rangeBound(urn CTSURN, node PassageNode, first bool, work Work) (string, error) {
+	id := urn.withPassage(node.CitationString())
+	if _, found := GetIndexByID(id, work); found {
+		return id, nil
+	}
+	if node.Subref.Exists {
+		return "", errors.New("passage " + id + " not found")
+	}
+	firstLeaf, lastLeaf, found := leafBounds(SplitCTS(id), work)
+	if !found {
+		return "", errors.New("passage " + id + " not found")
+	}
+	if first {
+		return firstLeaf, nil
+	}
+	return lastLeaf, nil
+}
+
+// passagesBetween returns the Passages from start to end in document order,
+// following the slice of an ordered Work and the links between Passages otherwise
+func passagesBetween(start, end string, work Work) ([]Passage, error) {
+	startindex, found := GetIndexByID(start, work)
+	if !found {
+		return []Passage{}, errors.New("Index of start passage not found")
+	}
+	endindex, found := GetIndexByID(end, work)
+	if !found {
+		return []Passage{}, errors.New("Index of end passage not found")
+	}
+	passages := []Passage{}
+	if work.Ordered {
+		if endindex < startindex {
+			return passages, errors.New("range ends before it starts")
+		}
+		return append(passages, work.Passages[startindex:endindex+1]...), nil
+	}
+	it := &PassageIterator{work: work, cursor: PassLoc{Exists: true, PassageID: start, Index: startindex}, stop: end, visited: map[int]bool{}}
+	for it.Next() {
+		passages = append(passages, it.Passage())
 	}
-	passIDs := strings.Split(urn.Passage, "-")
-	if len(passIDs) != 2 {
-		return "", "", errors.New("invalid urn")
+	if err := it.Err(); err != nil {
+		return []Passage{}, err
 	}
-	start = strings.Join([]string{urn.Base, urn.Protocol, urn.Namespace, urn.Work, passIDs[0]}, ":")
-	end = strings.Join([]string{urn.Base, urn.Protocol, urn.Namespace, urn.Work, passIDs[1]}, ":")
-	err = nil
-	return
+	return passages, nil
 }
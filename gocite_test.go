@@ -1,15 +1,18 @@
 package gocite_test
 
 import (
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 
-	"gociteDev/gocite"
+	"github.com/ThomasK81/gocite"
 )
 
 type oldTestPassage struct {
 	PassageID               string
 	Range                   bool
-	Text                    gocite.EncText
+	Text                    string
 	Index                   int
 	First, Last, Prev, Next gocite.PassLoc
 }
@@ -17,7 +20,7 @@ type oldTestPassage struct {
 type newTestPassage struct {
 	PassageID  string
 	Range      bool
-	Text       gocite.EncText
+	Text       string
 	Index      int
 	Prev, Next gocite.PassLoc
 }
@@ -35,6 +38,20 @@ type newTestWork struct {
 	First, Last gocite.PassLoc
 }
 
+// txtAnalysis wraps a plain string as the "txt" tokenisation of a Passage
+func txtAnalysis(txt string) []gocite.Tokenisation {
+	return []gocite.Tokenisation{{ID: "txt", Array: gocite.ArrayToken{CharRepres: []string{txt}}}}
+}
+
+// passageText returns the text stored in the "txt" tokenisation of a Passage
+func passageText(p gocite.Passage) string {
+	index, found := gocite.FindTextTokens(p)
+	if !found {
+		return ""
+	}
+	return strings.Join(p.Analysis[index].Array.CharRepres, "")
+}
+
 type URNTestpair struct {
 	input                  string
 	outputSplit            gocite.CTSURN
@@ -61,11 +78,23 @@ type newWorkTestgroup struct {
 type extractgroup struct {
 	input  string
 	answer []gocite.TextAndID
+	err    string
 }
 
 var URNtests = []URNTestpair{
-	{input: "urn:cts:collection:workgroup.work:1-27", outputSplit: gocite.CTSURN{ID: "urn:cts:collection:workgroup.work:1-27", Base: "urn", Protocol: "cts", Namespace: "collection", Work: "workgroup.work", Passage: "1-27"}, outputRange: true, outputCTS: true},
-	{input: "urn:cts:collection:workgroup.work:27.3", outputSplit: gocite.CTSURN{ID: "urn:cts:collection:workgroup.work:27.3", Base: "urn", Protocol: "cts", Namespace: "collection", Work: "workgroup.work", Passage: "27.3"}, outputRange: false, outputCTS: true},
+	{input: "urn:cts:collection:workgroup.work:1-27", outputSplit: gocite.CTSURN{ID: "urn:cts:collection:workgroup.work:1-27", Base: "urn", Protocol: "cts", Namespace: "collection", Work: "workgroup.work", Passage: "1-27",
+		Textgroup: "workgroup", WorkPart: "work",
+		Start: gocite.PassageNode{ID: "1", Citation: []string{"1"}},
+		End:   gocite.PassageNode{ID: "27", Citation: []string{"27"}},
+		Range: true}, outputRange: true, outputCTS: true},
+	{input: "urn:cts:collection:workgroup.work:27.3", outputSplit: gocite.CTSURN{ID: "urn:cts:collection:workgroup.work:27.3", Base: "urn", Protocol: "cts", Namespace: "collection", Work: "workgroup.work", Passage: "27.3",
+		Textgroup: "workgroup", WorkPart: "work",
+		Start: gocite.PassageNode{ID: "27.3", Citation: []string{"27", "3"}}}, outputRange: false, outputCTS: true},
+	{input: "urn:cts:greekLit:tlg0012.tlg001.msA.tokens:1.1@μῆνιν[1]-1.2@ἄειδε", outputSplit: gocite.CTSURN{ID: "urn:cts:greekLit:tlg0012.tlg001.msA.tokens:1.1@μῆνιν[1]-1.2@ἄειδε", Base: "urn", Protocol: "cts", Namespace: "greekLit", Work: "tlg0012.tlg001.msA.tokens", Passage: "1.1@μῆνιν[1]-1.2@ἄειδε",
+		Textgroup: "tlg0012", WorkPart: "tlg001", Version: "msA", Exemplar: "tokens",
+		Start: gocite.PassageNode{ID: "1.1@μῆνιν[1]", Citation: []string{"1", "1"}, Subref: gocite.Subreference{Exists: true, Token: "μῆνιν", Index: 1}},
+		End:   gocite.PassageNode{ID: "1.2@ἄειδε", Citation: []string{"1", "2"}, Subref: gocite.Subreference{Exists: true, Token: "ἄειδε"}},
+		Range: true}, outputRange: true, outputCTS: true},
 	{input: "not:cts:collection:workgroup.work:27.3", outputSplit: gocite.CTSURN{ID: "not:cts:collection:workgroup.work:27.3", InValid: true}, outputRange: false, outputCTS: false}}
 
 var IDfeatureTests = []testIDfeaturesTestpair{
@@ -78,121 +107,101 @@ var IDfeatureTests = []testIDfeaturesTestpair{
 var oldFirstPassage = oldTestPassage{
 	PassageID: "urn:cts:collection:workgroup.work:1",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is the first node.",
-	},
-	Index: 0,
-	First: gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
-	Last:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
-	Prev:  gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
-	Next:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2-4", Index: 1},
+	Text:      "This is the first node.",
+	Index:     0,
+	First:     gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Last:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
+	Prev:      gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
+	Next:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2-4", Index: 1},
 }
 
 var newFirstPassage = gocite.Passage{
 	PassageID: "urn:cts:collection:workgroup.work:1",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is the first node.",
-	},
-	Index: 0,
-	Prev:  gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
-	Next:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2-4", Index: 1},
+	Analysis:  txtAnalysis("This is the first node."),
+	Index:     0,
+	Prev:      gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
+	Next:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2-4", Index: 1},
 }
 
 var oldFirstPassageChange = oldTestPassage{
 	PassageID: "urn:cts:collection:workgroup.work:1",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is the first node.",
-	},
-	Index: 0,
-	First: gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
-	Last:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
-	Prev:  gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
-	Next:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
+	Text:      "This is the first node.",
+	Index:     0,
+	First:     gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Last:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
+	Prev:      gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
+	Next:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
 }
 
 var newFirstPassageChange = gocite.Passage{
 	PassageID: "urn:cts:collection:workgroup.work:1",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is the first node.",
-	},
-	Index: 0,
-	Prev:  gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
-	Next:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
+	Analysis:  txtAnalysis("This is the first node."),
+	Index:     0,
+	Prev:      gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
+	Next:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
 }
 
 var oldSecondPassage = oldTestPassage{
 	PassageID: "urn:cts:collection:workgroup.work:2-4",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is. the second. node.",
-	},
-	Index: 1,
-	First: gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
-	Last:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
-	Prev:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
-	Next:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
+	Text:      "This is. the second. node.",
+	Index:     1,
+	First:     gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Last:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
+	Prev:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Next:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
 }
 
 var newSecondPassage = gocite.Passage{
 	PassageID: "urn:cts:collection:workgroup.work:2-4",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is. the second. node.",
-	},
-	Index: 1,
-	Prev:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
-	Next:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
+	Analysis:  txtAnalysis("This is. the second. node."),
+	Index:     1,
+	Prev:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Next:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
 }
 
 var oldThirdPassage = oldTestPassage{
 	PassageID: "urn:cts:collection:workgroup.work:5",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is the third node.",
-	},
-	Index: 2,
-	First: gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
-	Last:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
-	Next:  gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
-	Prev:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2-4", Index: 1},
+	Text:      "This is the third node.",
+	Index:     2,
+	First:     gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Last:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
+	Next:      gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
+	Prev:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2-4", Index: 1},
 }
 
 var newThirdPassage = gocite.Passage{
 	PassageID: "urn:cts:collection:workgroup.work:5",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is the third node.",
-	},
-	Index: 2,
-	Next:  gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
-	Prev:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2-4", Index: 1},
+	Analysis:  txtAnalysis("This is the third node."),
+	Index:     2,
+	Next:      gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
+	Prev:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2-4", Index: 1},
 }
 
 var oldThirdPassageChange = oldTestPassage{
 	PassageID: "urn:cts:collection:workgroup.work:5",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is the third node.",
-	},
-	Index: 2,
-	First: gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
-	Last:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
-	Next:  gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
-	Prev:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Text:      "This is the third node.",
+	Index:     2,
+	First:     gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Last:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:5", Index: 2},
+	Next:      gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
+	Prev:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
 }
 
 var newThirdPassageChange = gocite.Passage{
 	PassageID: "urn:cts:collection:workgroup.work:5",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is the third node.",
-	},
-	Index: 2,
-	Next:  gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
-	Prev:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Analysis:  txtAnalysis("This is the third node."),
+	Index:     2,
+	Next:      gocite.PassLoc{Exists: false, PassageID: "", Index: 0},
+	Prev:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
 }
 
 //The testcorpora should get some more spelling names, according to the tasks they fulfill or possibly some documentation
@@ -219,7 +228,7 @@ var newTestcorpus = gocite.Work{
 	Ordered: true,
 }
 
-//testcorpus2 is used to tests if sorting works with an empty passage in a work
+// testcorpus2 is used to tests if sorting works with an empty passage in a work
 var oldTestcorpus2 = oldTestWork{
 	WorkID: "urn:cts:collection:workgroup.work:",
 	Passages: []oldTestPassage{
@@ -270,14 +279,14 @@ var newTestcorpus3 = gocite.Work{
 	WorkID: "urn:cts:collection:workgroup.work:",
 	Passages: []gocite.Passage{
 		{PassageID: newFirstPassageChange.PassageID,
-			Range: newFirstPassageChange.Range,
-			Text:  newFirstPassageChange.Text, Index: 0,
+			Range:    newFirstPassageChange.Range,
+			Analysis: newFirstPassageChange.Analysis, Index: 0,
 			Next: gocite.PassLoc{Exists: true,
 				PassageID: newThirdPassageChange.PassageID, Index: 1}},
 		{PassageID: newThirdPassageChange.PassageID,
-			Range: newThirdPassageChange.Range,
-			Text:  newThirdPassageChange.Text,
-			Index: 1,
+			Range:    newThirdPassageChange.Range,
+			Analysis: newThirdPassageChange.Analysis,
+			Index:    1,
 			Prev: gocite.PassLoc{Exists: true,
 				PassageID: newFirstPassage.PassageID, Index: 0}},
 	},
@@ -303,7 +312,7 @@ var newTestcorpus4 = gocite.Work{
 	Last:    gocite.PassLoc{Exists: true, PassageID: oldThirdPassageChange.PassageID, Index: 1},
 	Ordered: true}
 
-//tescorpus5 is used to tests if sorting works with unordered passages in a work
+// tescorpus5 is used to tests if sorting works with unordered passages in a work
 var oldTestcorpus5 = oldTestWork{
 	WorkID: "urn:cts:collection:workgroup.work:",
 	Passages: []oldTestPassage{
@@ -317,9 +326,9 @@ var oldTestcorpus5 = oldTestWork{
 var newTestcorpus5 = gocite.Work{
 	WorkID: "urn:cts:collection:workgroup.work:",
 	Passages: []gocite.Passage{
-		{PassageID: newFirstPassageChange.PassageID, Range: newFirstPassageChange.Range, Text: newFirstPassageChange.Text, Index: 0, Prev: newFirstPassageChange.Prev, Next: gocite.PassLoc{Exists: true, PassageID: newSecondPassage.PassageID, Index: 2}},
-		{PassageID: newThirdPassageChange.PassageID, Range: newThirdPassageChange.Range, Text: newThirdPassageChange.Text, Index: 2, Prev: gocite.PassLoc{Exists: true, PassageID: newSecondPassage.PassageID, Index: 2}, Next: gocite.PassLoc{}},
-		{PassageID: newSecondPassage.PassageID, Range: newSecondPassage.Range, Text: newSecondPassage.Text, Index: 1, Prev: gocite.PassLoc{Exists: true, PassageID: newFirstPassageChange.PassageID, Index: 0}, Next: gocite.PassLoc{Exists: true, PassageID: newThirdPassage.PassageID, Index: 1}},
+		{PassageID: newFirstPassageChange.PassageID, Range: newFirstPassageChange.Range, Analysis: newFirstPassageChange.Analysis, Index: 0, Prev: newFirstPassageChange.Prev, Next: gocite.PassLoc{Exists: true, PassageID: newSecondPassage.PassageID, Index: 2}},
+		{PassageID: newThirdPassageChange.PassageID, Range: newThirdPassageChange.Range, Analysis: newThirdPassageChange.Analysis, Index: 2, Prev: gocite.PassLoc{Exists: true, PassageID: newSecondPassage.PassageID, Index: 2}, Next: gocite.PassLoc{}},
+		{PassageID: newSecondPassage.PassageID, Range: newSecondPassage.Range, Analysis: newSecondPassage.Analysis, Index: 1, Prev: gocite.PassLoc{Exists: true, PassageID: newFirstPassageChange.PassageID, Index: 0}, Next: gocite.PassLoc{Exists: true, PassageID: newThirdPassage.PassageID, Index: 1}},
 	},
 	First:   oldFirstPassageChange.First,
 	Last:    oldFirstPassageChange.Last,
@@ -339,9 +348,9 @@ var oldTestcorpus6 = oldTestWork{
 var newTestcorpus6 = gocite.Work{
 	WorkID: "urn:cts:collection:workgroup.work:",
 	Passages: []gocite.Passage{
-		{PassageID: newFirstPassageChange.PassageID, Range: newFirstPassageChange.Range, Text: newFirstPassageChange.Text, Index: 0, Next: gocite.PassLoc{Exists: true, PassageID: newSecondPassage.PassageID, Index: 1}},
-		{PassageID: newSecondPassage.PassageID, Range: newSecondPassage.Range, Text: newSecondPassage.Text, Index: 1, Next: gocite.PassLoc{Exists: true, PassageID: newThirdPassageChange.PassageID, Index: 2}, Prev: gocite.PassLoc{Exists: true, PassageID: newFirstPassageChange.PassageID, Index: 0}},
-		{PassageID: newThirdPassageChange.PassageID, Range: newThirdPassageChange.Range, Text: newThirdPassageChange.Text, Index: 2, Prev: gocite.PassLoc{Exists: true, PassageID: newSecondPassage.PassageID, Index: 1}},
+		{PassageID: newFirstPassageChange.PassageID, Range: newFirstPassageChange.Range, Analysis: newFirstPassageChange.Analysis, Index: 0, Next: gocite.PassLoc{Exists: true, PassageID: newSecondPassage.PassageID, Index: 1}},
+		{PassageID: newSecondPassage.PassageID, Range: newSecondPassage.Range, Analysis: newSecondPassage.Analysis, Index: 1, Next: gocite.PassLoc{Exists: true, PassageID: newThirdPassageChange.PassageID, Index: 2}, Prev: gocite.PassLoc{Exists: true, PassageID: newFirstPassageChange.PassageID, Index: 0}},
+		{PassageID: newThirdPassageChange.PassageID, Range: newThirdPassageChange.Range, Analysis: newThirdPassageChange.Analysis, Index: 2, Prev: gocite.PassLoc{Exists: true, PassageID: newSecondPassage.PassageID, Index: 1}},
 	},
 	First:   gocite.PassLoc{Exists: true, PassageID: newFirstPassageChange.PassageID, Index: 0},
 	Last:    gocite.PassLoc{Exists: true, PassageID: newThirdPassageChange.PassageID, Index: 2},
@@ -359,8 +368,8 @@ var oldTestcorpus7 = oldTestWork{
 var newTestcorpus7 = gocite.Work{
 	WorkID: "urn:cts:collection:workgroup.work:",
 	Passages: []gocite.Passage{
-		{PassageID: newFirstPassageChange.PassageID, Range: false, Text: newFirstPassageChange.Text, Index: 0, Next: gocite.PassLoc{Exists: true, PassageID: newThirdPassageChange.PassageID, Index: 1}},
-		{PassageID: newThirdPassageChange.PassageID, Range: false, Text: newThirdPassageChange.Text, Index: 1, Prev: gocite.PassLoc{Exists: true, PassageID: newFirstPassageChange.PassageID, Index: 0}},
+		{PassageID: newFirstPassageChange.PassageID, Range: false, Analysis: newFirstPassageChange.Analysis, Index: 0, Next: gocite.PassLoc{Exists: true, PassageID: newThirdPassageChange.PassageID, Index: 1}},
+		{PassageID: newThirdPassageChange.PassageID, Range: false, Analysis: newThirdPassageChange.Analysis, Index: 1, Prev: gocite.PassLoc{Exists: true, PassageID: newFirstPassageChange.PassageID, Index: 0}},
 	},
 	First:   gocite.PassLoc{Exists: true, PassageID: newFirstPassageChange.PassageID, Index: 0},
 	Last:    gocite.PassLoc{Exists: true, PassageID: newThirdPassageChange.PassageID, Index: 1},
@@ -376,12 +385,13 @@ var oldTestcorpus8 = oldTestWork{
 	Ordered: false,
 }
 
+// newTestcorpus8 is newTestcorpus7 after InsertPassage of newSecondPassage, linked to both its neighbours like in oldTestcorpus8
 var newTestcorpus8 = gocite.Work{
 	WorkID: "urn:cts:collection:workgroup.work:",
 	Passages: []gocite.Passage{
-		{PassageID: newFirstPassageChange.PassageID, Range: false, Text: newFirstPassageChange.Text, Index: 0, Next: gocite.PassLoc{Exists: true, PassageID: newSecondPassage.PassageID, Index: 2}},
-		{PassageID: newThirdPassageChange.PassageID, Range: false, Text: newThirdPassageChange.Text, Index: 1, Prev: gocite.PassLoc{Exists: true, PassageID: newSecondPassage.PassageID, Index: 2}},
-		{PassageID: newSecondPassage.PassageID, Range: newSecondPassage.Range, Text: newSecondPassage.Text, Index: 2, Next: gocite.PassLoc{Exists: true, PassageID: newThirdPassageChange.PassageID, Index: 1}},
+		{PassageID: newFirstPassageChange.PassageID, Range: false, Analysis: newFirstPassageChange.Analysis, Index: 0, Next: gocite.PassLoc{Exists: true, PassageID: newSecondPassage.PassageID, Index: 2}},
+		{PassageID: newThirdPassageChange.PassageID, Range: false, Analysis: newThirdPassageChange.Analysis, Index: 1, Prev: gocite.PassLoc{Exists: true, PassageID: newSecondPassage.PassageID, Index: 2}},
+		{PassageID: newSecondPassage.PassageID, Range: newSecondPassage.Range, Analysis: newSecondPassage.Analysis, Index: 2, Prev: gocite.PassLoc{Exists: true, PassageID: newFirstPassageChange.PassageID, Index: 0}, Next: gocite.PassLoc{Exists: true, PassageID: newThirdPassageChange.PassageID, Index: 1}},
 	},
 
 	First:   gocite.PassLoc{Exists: true, PassageID: newFirstPassageChange.PassageID, Index: 0},
@@ -394,7 +404,7 @@ var OldURNtests3 = []oldWorkTestgroup{ //slice format necessary?
 }
 
 var URNtests3 = []newWorkTestgroup{ //slice format necessary?
-	{inputCorpus: newTestcorpus, inputID: "urn:cts:collection:workgroup.work:2-4", outputCorpus: newTestcorpus2},
+	{inputCorpus: newTestcorpus, inputID: "urn:cts:collection:workgroup.work:2-4", outputCorpus: newTestcorpus3},
 }
 
 var oldURNtests4a = []oldWorkTestgroup{
@@ -428,7 +438,7 @@ var URNtests5 = []newWorkTestgroup{ //slice format necessary?
 func TestSplitCTS(t *testing.T) {
 	for _, pair := range URNtests {
 		v := gocite.SplitCTS(pair.input)
-		if v != pair.outputSplit {
+		if !reflect.DeepEqual(v, pair.outputSplit) {
 			t.Error(
 				"For", pair.input,
 				"expected", pair.outputSplit,
@@ -438,6 +448,29 @@ func TestSplitCTS(t *testing.T) {
 	}
 }
 
+func TestCTSURNString(t *testing.T) {
+	for _, pair := range URNtests {
+		v := gocite.SplitCTS(pair.input).String()
+		if v != pair.input {
+			t.Error(
+				"For", pair.input,
+				"expected", pair.input,
+				"got", v,
+			)
+		}
+	}
+	for _, pair := range IDfeatureTests {
+		v := gocite.SplitCTS(pair.input).String()
+		if v != pair.input {
+			t.Error(
+				"For", pair.input,
+				"expected", pair.input,
+				"got", v,
+			)
+		}
+	}
+}
+
 func TestIsRange(t *testing.T) {
 	for _, pair := range URNtests {
 		v := gocite.IsRange(pair.input)
@@ -519,25 +552,37 @@ func TestIsExemplarID(t *testing.T) {
 func TestDelPlassage(t *testing.T) {
 	for _, workTestgroup := range URNtests3 {
 		sortedWork, _ := gocite.DelPassage(workTestgroup.inputID, workTestgroup.inputCorpus)
-		baseWork := oldTestWork{Ordered: sortedWork.Ordered}
-		compareWork := oldTestWork{Ordered: workTestgroup.inputCorpus.Ordered}
-		if baseWork.Ordered == compareWork.Ordered {
+		if sortedWork.Ordered != workTestgroup.outputCorpus.Ordered {
 			t.Error(
 				"For deleting", workTestgroup.inputID,
-				"expected", !workTestgroup.inputCorpus.Ordered,
-				"got", workTestgroup.inputCorpus.Ordered,
+				"expected", workTestgroup.outputCorpus.Ordered,
+				"got", sortedWork.Ordered,
+			)
+		}
+		if len(sortedWork.Passages) != len(workTestgroup.outputCorpus.Passages) {
+			t.Fatal(
+				"For deleting", workTestgroup.inputID,
+				"expected", len(workTestgroup.outputCorpus.Passages), "passages",
+				"got", len(sortedWork.Passages),
+			)
+		}
+		if sortedWork.First != workTestgroup.outputCorpus.First || sortedWork.Last != workTestgroup.outputCorpus.Last {
+			t.Error(
+				"For deleting", workTestgroup.inputID,
+				"expected First and Last", workTestgroup.outputCorpus.First, workTestgroup.outputCorpus.Last,
+				"got", sortedWork.First, sortedWork.Last,
 			)
 		}
 		for i := range sortedWork.Passages {
 			basePassage := newTestPassage{PassageID: sortedWork.Passages[i].PassageID,
 				Range: sortedWork.Passages[i].Range,
-				Text:  sortedWork.Passages[i].Text,
+				Text:  passageText(sortedWork.Passages[i]),
 				Index: sortedWork.Passages[i].Index,
 				Prev:  sortedWork.Passages[i].Prev,
 				Next:  sortedWork.Passages[i].Next}
 			comparePassage := newTestPassage{PassageID: workTestgroup.outputCorpus.Passages[i].PassageID,
 				Range: workTestgroup.outputCorpus.Passages[i].Range,
-				Text:  workTestgroup.outputCorpus.Passages[i].Text,
+				Text:  passageText(workTestgroup.outputCorpus.Passages[i]),
 				Index: workTestgroup.outputCorpus.Passages[i].Index,
 				Prev:  workTestgroup.outputCorpus.Passages[i].Prev,
 				Next:  workTestgroup.outputCorpus.Passages[i].Next}
@@ -552,6 +597,59 @@ func TestDelPlassage(t *testing.T) {
 	}
 }
 
+// textlessWork returns a Work with the citations of testCitationWork whose Passages have no text
+func textlessWork(t *testing.T) gocite.Work {
+	passages := []gocite.Passage{}
+	for _, citation := range []string{"1.1", "1.2", "1.3", "2.1", "2.2"} {
+		passages = append(passages, gocite.Passage{PassageID: citationWork + citation})
+	}
+	work, err := gocite.InsertPassagesBefore("", passages, gocite.Work{WorkID: citationWork})
+	if err != nil {
+		t.Fatal("Error calling InsertPassagesBefore: ", err)
+	}
+	return work
+}
+
+type delRangeTestgroup struct {
+	input    string
+	expected []string
+}
+
+var delRangeTests = []delRangeTestgroup{
+	{input: "1.2-2.1", expected: []string{"1.1", "2.2"}},
+	{input: "1", expected: []string{"2.1", "2.2"}},
+	{input: "1.3-2", expected: []string{"1.1", "1.2"}},
+}
+
+func TestDelRange(t *testing.T) {
+	for _, test := range delRangeTests {
+		work := testCitationWork(t)
+		result, err := gocite.DelRange(citationWork+test.input, work)
+		if err != nil {
+			t.Fatal("Error calling DelRange: ", err)
+		}
+		checkEdit(t, "deleting "+test.input, work, result, test.expected)
+	}
+	textless := textlessWork(t)
+	for _, test := range delRangeTests {
+		result, err := gocite.DelRange(citationWork+test.input, textless)
+		if ids := documentIDs(t, result); err != nil || !reflect.DeepEqual(ids, test.expected) {
+			t.Error("For", test.input, "without text expected", test.expected, "got", ids, err)
+		}
+	}
+	work := testCitationWork(t)
+	for _, input := range []string{"3", "1.1@Μῆνιν-1.2", ""} {
+		if _, err := gocite.DelRange(citationWork+input, work); err == nil {
+			t.Error("For", input, "expected an error")
+		}
+	}
+	work, _ = gocite.DelFirstPassage(work)
+	work, _ = gocite.DelLastPassage(work)
+	if len(work.Passages) != 3 || gocite.GetFirst(work).PassageID != citationWork+"1.2" || gocite.GetLast(work).PassageID != citationWork+"2.1" {
+		t.Error("unexpected work after deleting first and last passage", work.Passages)
+	}
+}
+
 func TestFindFirstIndex(t *testing.T) {
 	for _, pair := range URNtests4a {
 		v, found := gocite.FindFirstIndex(pair.inputCorpus)
@@ -580,7 +678,7 @@ func TestSortPassages(t *testing.T) {
 			var tempInputPassage = gocite.Passage{
 				PassageID: workTestgroup.inputCorpus.Passages[k].PassageID,
 				Range:     workTestgroup.inputCorpus.Passages[k].Range,
-				Text:      workTestgroup.inputCorpus.Passages[k].Text,
+				Text:      passageText(workTestgroup.inputCorpus.Passages[k]),
 				Index:     workTestgroup.inputCorpus.Passages[k].Index,
 				Prev:      workTestgroup.inputCorpus.Passages[k].Prev,
 				Next:      workTestgroup.inputCorpus.Passages[k].Next}
@@ -608,13 +706,13 @@ func TestSortPassages(t *testing.T) {
 		for i := range sortedWork.Passages { //should run twice
 			basePassage := newTestPassage{PassageID: sortedWork.Passages[i].PassageID,
 				Range: sortedWork.Passages[i].Range,
-				Text:  sortedWork.Passages[i].Text,
+				Text:  passageText(sortedWork.Passages[i]),
 				Index: sortedWork.Passages[i].Index,
 				Prev:  sortedWork.Passages[i].Prev,
 				Next:  sortedWork.Passages[i].Next}
 			comparePassage := newTestPassage{PassageID: workTestgroup.outputCorpus.Passages[i].PassageID,
 				Range: workTestgroup.outputCorpus.Passages[i].Range,
-				Text:  workTestgroup.outputCorpus.Passages[i].Text,
+				Text:  passageText(workTestgroup.outputCorpus.Passages[i]),
 				Index: workTestgroup.outputCorpus.Passages[i].Index,
 				Prev:  workTestgroup.outputCorpus.Passages[i].Prev,
 				Next:  workTestgroup.outputCorpus.Passages[i].Next}
@@ -643,13 +741,13 @@ func TestSortPassages(t *testing.T) {
 		for i := range work.Passages {
 			basePassage := newTestPassage{PassageID: work.Passages[i].PassageID,
 				Range: work.Passages[i].Range,
-				Text:  work.Passages[i].Text,
+				Text:  passageText(work.Passages[i]),
 				Index: work.Passages[i].Index,
 				Prev:  work.Passages[i].Prev,
 				Next:  work.Passages[i].Next}
 			comparePassage := newTestPassage{PassageID: workTestgroup.outputCorpus.Passages[i].PassageID,
 				Range: workTestgroup.outputCorpus.Passages[i].Range,
-				Text:  workTestgroup.outputCorpus.Passages[i].Text,
+				Text:  passageText(workTestgroup.outputCorpus.Passages[i]),
 				Index: workTestgroup.outputCorpus.Passages[i].Index,
 				Prev:  workTestgroup.outputCorpus.Passages[i].Prev,
 				Next:  workTestgroup.outputCorpus.Passages[i].Next}
@@ -680,13 +778,13 @@ func TestInsertPassage(t *testing.T) {
 	for i := range insertedWork.Passages {
 		basePassage := oldTestPassage{PassageID: insertedWork.Passages[i].PassageID,
 			Range: insertedWork.Passages[i].Range,
-			Text:  insertedWork.Passages[i].Text,
+			Text:  passageText(insertedWork.Passages[i]),
 			Index: insertedWork.Passages[i].Index,
 			Prev:  insertedWork.Passages[i].Prev,
 			Next:  insertedWork.Passages[i].Next}
 		comparePassage := oldTestPassage{PassageID: URNtests5[0].outputCorpus.Passages[i].PassageID,
 			Range: URNtests5[0].outputCorpus.Passages[i].Range,
-			Text:  URNtests5[0].outputCorpus.Passages[i].Text,
+			Text:  passageText(URNtests5[0].outputCorpus.Passages[i]),
 			Index: URNtests5[0].outputCorpus.Passages[i].Index,
 			Prev:  URNtests5[0].outputCorpus.Passages[i].Prev,
 			Next:  URNtests5[0].outputCorpus.Passages[i].Next}
@@ -705,6 +803,12 @@ func TestExtractTextByID(t *testing.T) {
 		request := extrtest[j].input
 		answer := extrtest[j].answer
 		v, err := gocite.ExtractTextByID(request, testExtrcorpus)
+		if extrtest[j].err != "" {
+			if err == nil || !strings.Contains(err.Error(), extrtest[j].err) {
+				t.Error("For test", request, "expected error", extrtest[j].err, "got", err)
+			}
+			continue
+		}
 		if err != nil {
 			t.Error(request, err)
 		}
@@ -763,36 +867,42 @@ var extrtest = []extractgroup{
 		}},
 	{
 		input: "urn:cts:collection:workgroup.work:2@is[2]-2@second",
+		err:   "occurrence 2 of is not found"},
+	{
+		input: "urn:cts:collection:workgroup.work:2@is-2@second",
 		answer: []gocite.TextAndID{
-			{ID: "urn:cts:collection:workgroup.work:2@is[2]-2@second", Text: "is. the second"},
+			{ID: "urn:cts:collection:workgroup.work:2@is-2@second", Text: "is. the second"},
 		}},
 	{
 		input: "urn:cts:collection:workgroup.work:1@is-3",
 		answer: []gocite.TextAndID{
-			{ID: "urn:cts:collection:workgroup.work:1@is", Text: "is is is the first node."},
+			{ID: "urn:cts:collection:workgroup.work:1@is", Text: "is is the first node."},
 			{ID: "urn:cts:collection:workgroup.work:2", Text: "This is. the second. node."},
 			{ID: "urn:cts:collection:workgroup.work:3", Text: "This is the third node."},
 		}},
 	{
 		input: "urn:cts:collection:workgroup.work:1@is[2]-3",
 		answer: []gocite.TextAndID{
-			{ID: "urn:cts:collection:workgroup.work:1@is[2]", Text: "is is the first node."},
+			{ID: "urn:cts:collection:workgroup.work:1@is[2]", Text: "is the first node."},
 			{ID: "urn:cts:collection:workgroup.work:2", Text: "This is. the second. node."},
 			{ID: "urn:cts:collection:workgroup.work:3", Text: "This is the third node."},
 		}},
 	{
 		input: "urn:cts:collection:workgroup.work:1@is[2]-3@third",
 		answer: []gocite.TextAndID{
-			{ID: "urn:cts:collection:workgroup.work:1@is[2]", Text: "is is the first node."},
+			{ID: "urn:cts:collection:workgroup.work:1@is[2]", Text: "is the first node."},
 			{ID: "urn:cts:collection:workgroup.work:2", Text: "This is. the second. node."},
 			{ID: "urn:cts:collection:workgroup.work:3@third", Text: "This is the third"},
 		}},
 	{
 		input: "urn:cts:collection:workgroup.work:1@is[2]-3@is[2]",
+		err:   "occurrence 2 of is not found"},
+	{
+		input: "urn:cts:collection:workgroup.work:1@is[2]-3@is",
 		answer: []gocite.TextAndID{
-			{ID: "urn:cts:collection:workgroup.work:1@is[2]", Text: "is is the first node."},
+			{ID: "urn:cts:collection:workgroup.work:1@is[2]", Text: "is the first node."},
 			{ID: "urn:cts:collection:workgroup.work:2", Text: "This is. the second. node."},
-			{ID: "urn:cts:collection:workgroup.work:3@is[2]", Text: "This is"},
+			{ID: "urn:cts:collection:workgroup.work:3@is", Text: "This is"},
 		}},
 }
 
@@ -809,71 +919,227 @@ var testExtrcorpus = gocite.Work{
 var oldPassageOne = oldTestPassage{
 	PassageID: "urn:cts:collection:workgroup.work:1",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is is the first node.",
-	},
-	Index: 0,
-	First: gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
-	Last:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:3", Index: 2},
-	Prev:  gocite.PassLoc{},
-	Next:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2", Index: 1},
+	Text:      "This is is the first node.",
+	Index:     0,
+	First:     gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Last:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:3", Index: 2},
+	Prev:      gocite.PassLoc{},
+	Next:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2", Index: 1},
 }
 
 var PassageOne = gocite.Passage{
 	PassageID: "urn:cts:collection:workgroup.work:1",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is is the first node.",
-	},
-	Index: 0,
-	Prev:  gocite.PassLoc{},
-	Next:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2", Index: 1},
+	Analysis:  txtAnalysis("This is is the first node."),
+	Index:     0,
+	Prev:      gocite.PassLoc{},
+	Next:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2", Index: 1},
 }
 
 var oldPassageTwo = oldTestPassage{
 	PassageID: "urn:cts:collection:workgroup.work:2",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is. the second. node.",
-	},
-	Index: 1,
-	First: gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
-	Last:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:3", Index: 2},
-	Prev:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
-	Next:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:3", Index: 2},
+	Text:      "This is. the second. node.",
+	Index:     1,
+	First:     gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Last:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:3", Index: 2},
+	Prev:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Next:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:3", Index: 2},
 }
 
 var PassageTwo = gocite.Passage{
 	PassageID: "urn:cts:collection:workgroup.work:2",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is. the second. node.",
-	},
-	Index: 1,
-	Prev:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
-	Next:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:3", Index: 2},
+	Analysis:  txtAnalysis("This is. the second. node."),
+	Index:     1,
+	Prev:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Next:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:3", Index: 2},
 }
 
 var oldPassageThree = oldTestPassage{
 	PassageID: "urn:cts:collection:workgroup.work:3",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is the third node.",
-	},
-	Index: 2,
-	First: gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
-	Last:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:3", Index: 2},
-	Prev:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2", Index: 1},
-	Next:  gocite.PassLoc{},
+	Text:      "This is the third node.",
+	Index:     2,
+	First:     gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:1", Index: 0},
+	Last:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:3", Index: 2},
+	Prev:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2", Index: 1},
+	Next:      gocite.PassLoc{},
 }
 
 var PassageThree = gocite.Passage{
 	PassageID: "urn:cts:collection:workgroup.work:3",
 	Range:     false,
-	Text: gocite.EncText{
-		TXT: "This is the third node.",
-	},
-	Index: 2,
-	Prev:  gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2", Index: 1},
-	Next:  gocite.PassLoc{},
+	Analysis:  txtAnalysis("This is the third node."),
+	Index:     2,
+	Prev:      gocite.PassLoc{Exists: true, PassageID: "urn:cts:collection:workgroup.work:2", Index: 1},
+	Next:      gocite.PassLoc{},
+}
+
+func TestGetIndexByID(t *testing.T) {
+	data, err := gocite.ReadCEX(strings.NewReader(testCEX))
+	if err != nil {
+		t.Fatal("Error calling ReadCEX: ", err)
+	}
+	work := data.Textgroups[0].Works[0]
+	inserted := gocite.Passage{PassageID: "urn:cts:greekLit:tlg0012.tlg001.msA:1.4", Analysis: txtAnalysis("test"),
+		Prev: gocite.PassLoc{Exists: true, PassageID: "urn:cts:greekLit:tlg0012.tlg001.msA:1.3"}}
+	work, err = gocite.InsertPassage(inserted, work)
+	if err != nil {
+		t.Fatal("Error calling InsertPassage: ", err)
+	}
+	work, err = gocite.DelPassage("urn:cts:greekLit:tlg0012.tlg001.msA:1.2", work)
+	if err != nil {
+		t.Fatal("Error calling DelPassage: ", err)
+	}
+	work.Passages = append(work.Passages, gocite.Passage{PassageID: "urn:cts:greekLit:tlg0012.tlg001.msA:2.1"})
+	work.Passages[1].PassageID = "urn:cts:greekLit:tlg0012.tlg001.msA:1.3a"
+	tests := []struct {
+		id    string
+		index int
+		found bool
+	}{
+		{id: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1", index: 0, found: true},
+		{id: "urn:cts:greekLit:tlg0012.tlg001.msA:1.2", index: 0, found: false},
+		{id: "urn:cts:greekLit:tlg0012.tlg001.msA:1.3", index: 0, found: false},
+		{id: "urn:cts:greekLit:tlg0012.tlg001.msA:1.3a", index: 1, found: true},
+		{id: "urn:cts:greekLit:tlg0012.tlg001.msA:1.4", index: 2, found: true},
+		{id: "urn:cts:greekLit:tlg0012.tlg001.msA:2.1", index: 3, found: true},
+	}
+	for _, test := range tests {
+		index, found := gocite.GetIndexByID(test.id, work)
+		if index != test.index || found != test.found {
+			t.Error("For", test.id, "expected", test.index, test.found, "got", index, found)
+		}
+	}
+	if next := gocite.GetNext("urn:cts:greekLit:tlg0012.tlg001.msA:1.1", work); next.PassageID != "urn:cts:greekLit:tlg0012.tlg001.msA:1.3a" {
+		t.Error("expected next passage urn:cts:greekLit:tlg0012.tlg001.msA:1.3a, got", next.PassageID)
+	}
+	work = gocite.BuildIDIndex(work)
+	work.Passages[0].PassageID = "urn:cts:greekLit:tlg0012.tlg001.msA:1.0"
+	if _, found := gocite.GetIndexByID("urn:cts:greekLit:tlg0012.tlg001.msA:1.0", work); found {
+		t.Error("expected urn:cts:greekLit:tlg0012.tlg001.msA:1.0 to be missing from the index until BuildIDIndex")
+	}
+	if index, found := gocite.GetIndexByID("urn:cts:greekLit:tlg0012.tlg001.msA:1.0", gocite.BuildIDIndex(work)); index != 0 || !found {
+		t.Error("For urn:cts:greekLit:tlg0012.tlg001.msA:1.0 expected 0 true, got", index, found)
+	}
+}
+
+// benchmarkWork returns a Work with n passages read from CEX
+// along with a copy of it that carries no ID index
+func benchmarkWork(b *testing.B, n int) (gocite.Work, gocite.Work) {
+	var cex strings.Builder
+	cex.WriteString("#!ctsdata\n")
+	for i := 1; i <= n; i++ {
+		cex.WriteString("urn:cts:greekLit:tlg0012.tlg001.msA:" + strconv.Itoa(i) + "#line " + strconv.Itoa(i) + "\n")
+	}
+	data, err := gocite.ReadCEX(strings.NewReader(cex.String()))
+	if err != nil {
+		b.Fatal("Error calling ReadCEX: ", err)
+	}
+	work := data.Textgroups[0].Works[0]
+	unindexed := gocite.Work{WorkID: work.WorkID, Passages: work.Passages, Ordered: work.Ordered, First: work.First, Last: work.Last}
+	return work, unindexed
+}
+
+func benchmarkGetPassageByID(b *testing.B, indexed bool) {
+	work, unindexed := benchmarkWork(b, 100000)
+	if !indexed {
+		work = unindexed
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id := "urn:cts:greekLit:tlg0012.tlg001.msA:" + strconv.Itoa(i%100000+1)
+		if _, err := gocite.GetPassageByID(id, work); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGetPassageByIDIndexed(b *testing.B)   { benchmarkGetPassageByID(b, true) }
+func BenchmarkGetPassageByIDUnindexed(b *testing.B) { benchmarkGetPassageByID(b, false) }
+
+// benchmarkGetIndexByIDMissing looks up PassageIDs a Work does not have,
+// which the ID index answers without searching the Passages
+func benchmarkGetIndexByIDMissing(b *testing.B, indexed bool) {
+	work, unindexed := benchmarkWork(b, 100000)
+	if !indexed {
+		work = unindexed
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id := "urn:cts:greekLit:tlg0012.tlg001.msA:" + strconv.Itoa(i%100000+1) + "a"
+		if _, found := gocite.GetIndexByID(id, work); found {
+			b.Fatal("found missing passage", id)
+		}
+	}
+}
+
+func BenchmarkGetIndexByIDMissingIndexed(b *testing.B)   { benchmarkGetIndexByIDMissing(b, true) }
+func BenchmarkGetIndexByIDMissingUnindexed(b *testing.B) { benchmarkGetIndexByIDMissing(b, false) }
+
+// BenchmarkExtractTextByIDContainers extracts the last books of a Work of 1000 books of 100 lines,
+// whose ends are citation nodes rather than Passages and missing from the ID index
+func BenchmarkExtractTextByIDContainers(b *testing.B) {
+	var cex strings.Builder
+	cex.WriteString("#!ctsdata\n")
+	for book := 1; book <= 1000; book++ {
+		for line := 1; line <= 100; line++ {
+			cex.WriteString("urn:cts:greekLit:tlg0012.tlg001.msA:" + strconv.Itoa(book) + "." + strconv.Itoa(line) + "#line " + strconv.Itoa(line) + "\n")
+		}
+	}
+	data, err := gocite.ReadCEX(strings.NewReader(cex.String()))
+	if err != nil {
+		b.Fatal("Error calling ReadCEX: ", err)
+	}
+	work := data.Textgroups[0].Works[0]
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gocite.ExtractTextByID("urn:cts:greekLit:tlg0012.tlg001.msA:999-1000", work); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkExtractUnordered extracts the last lines of a Work, whose ends are found
+// in constant time with the ID index and by a scan through the whole Work without it
+func benchmarkExtractUnordered(b *testing.B, indexed bool) {
+	work, unindexed := benchmarkWork(b, 100000)
+	if !indexed {
+		work = unindexed
+	}
+	work.Ordered = false
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gocite.ExtractTextByID("urn:cts:greekLit:tlg0012.tlg001.msA:99901-100000", work); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExtractTextByIDUnorderedIndexed(b *testing.B)   { benchmarkExtractUnordered(b, true) }
+func BenchmarkExtractTextByIDUnorderedUnindexed(b *testing.B) { benchmarkExtractUnordered(b, false) }
+
+func BenchmarkInsertPassage(b *testing.B) {
+	work, _ := benchmarkWork(b, 100000)
+	passage := gocite.Passage{PassageID: "urn:cts:greekLit:tlg0012.tlg001.msA:50000a",
+		Prev: gocite.PassLoc{PassageID: "urn:cts:greekLit:tlg0012.tlg001.msA:50000"},
+		Next: gocite.PassLoc{PassageID: "urn:cts:greekLit:tlg0012.tlg001.msA:50001"}}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gocite.InsertPassage(passage, work); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDelPassage(b *testing.B) {
+	work, _ := benchmarkWork(b, 100000)
+	work.Ordered = false
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := gocite.DelPassage("urn:cts:greekLit:tlg0012.tlg001.msA:50000", work); err != nil {
+			b.Fatal(err)
+		}
+	}
 }
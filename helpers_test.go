@@ -0,0 +1,41 @@
+package gocite_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+// documentIDs returns the citations of the Passages of a Work in document order
+func documentIDs(t *testing.T, work gocite.Work) []string {
+	ids := []string{}
+	for cursor := work.First; cursor.Exists; cursor = work.Passages[cursor.Index].Next {
+		ids = append(ids, strings.TrimPrefix(cursor.PassageID, citationWork))
+		if len(ids) > len(work.Passages) {
+			t.Fatal("work is loopy")
+		}
+	}
+	return ids
+}
+
+// checkEdit checks that result, an edit of work, is a valid Work with the expected citations
+// in document order and that work is still the Work testCitationWork returns
+func checkEdit(t *testing.T, label string, work, result gocite.Work, expected []string) {
+	if ids := documentIDs(t, result); !reflect.DeepEqual(ids, expected) {
+		t.Error("For", label, "expected", expected, "got", ids)
+	}
+	if report := gocite.ValidateWork(result); !report.Valid() {
+		t.Error("For", label, "got an invalid work", report.Error())
+	}
+	if !reflect.DeepEqual(exported(work), exported(testCitationWork(t))) {
+		t.Error("For", label, "expected the original Work to be unchanged")
+	}
+}
+
+// exported returns the exported fields of a Work, leaving out its indices, which may be built on first use
+func exported(work gocite.Work) gocite.Work {
+	return gocite.Work{WorkID: work.WorkID, Passages: work.Passages, Ordered: work.Ordered,
+		First: work.First, Last: work.Last, CitationScheme: work.CitationScheme}
+}
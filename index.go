@@ -0,0 +1,77 @@
+package gocite
+
+import (
+	"sort"
+)
+
+// maxIndexLayers is the number of layers an ID index may have before the next edit rebuilds it
+const maxIndexLayers = 16
+
+// passageIndex maps the PassageIDs of a Work to their slice indices. It is never changed once built:
+// an edit puts the PassageIDs it adds and the slice indices it removes into a new layer on top of
+// the index of the Work it is given, which the Works share. Each layer holds all PassageIDs of
+// the Passages slice it was created for, so that a PassageID missing from it is not in that slice.
+type passageIndex struct {
+	ids      map[string]int
+	removed  []int
+	parent   *passageIndex
+	layers   int
+	passages []Passage
+}
+
+// newPassageIndex returns an index of the PassageIDs of passages
+func newPassageIndex(passages []Passage) *passageIndex {
+	ids := make(map[string]int, len(passages))
+	for i := range passages {
+		ids[passages[i].PassageID] = i
+	}
+	return &passageIndex{ids: ids, layers: 1, passages: passages}
+}
+
+// covers tests whether the index was created for the Passages slice passages,
+// rather than for another one or one changed by hand in length
+func (index *passageIndex) covers(passages []Passage) bool {
+	if index == nil || len(index.passages) != len(passages) {
+		return false
+	}
+	return len(passages) == 0 || &index.passages[0] == &passages[0]
+}
+
+// over returns the index for passages, a copy of the Passages slice the index was created for
+func (index *passageIndex) over(passages []Passage) *passageIndex {
+	if index == nil {
+		return nil
+	}
+	copied := *index
+	copied.passages = passages
+	return &copied
+}
+
+// lookup returns the slice index of a PassageID along with a bool whether the index holds it.
+// Slice indices of the layers below are moved down by the number of slice indices removed before them.
+func (index *passageIndex) lookup(passageID string) (int, bool) {
+	if index == nil {
+		return 0, false
+	}
+	if i, found := index.ids[passageID]; found {
+		return i, true
+	}
+	i, found := index.parent.lookup(passageID)
+	if !found || len(index.removed) == 0 {
+		return i, found
+	}
+	shift := sort.SearchInts(index.removed, i)
+	if shift < len(index.removed) && index.removed[shift] == i {
+		return 0, false
+	}
+	return i - shift, true
+}
+
+// with returns the index of passages, the Passages of a Work after an edit that removed the slice indices
+// removed, in ascending order, and then added ids. A missing or deep index is rebuilt from passages instead.
+func (index *passageIndex) with(ids map[string]int, removed []int, passages []Passage) *passageIndex {
+	if index == nil || index.layers >= maxIndexLayers {
+		return newPassageIndex(passages)
+	}
+	return &passageIndex{ids: ids, removed: removed, parent: index, layers: index.layers + 1, passages: passages}
+}
@@ -0,0 +1,128 @@
+package gocite
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// InsertPassagesAfter inserts a sequence of Passages into a Work after the Passage passageID,
+// linking them to each other in the given order and to the neighbours of passageID.
+// An empty passageID inserts them before the first Passage of the Work.
+// First, Last and the ID index are updated, Analysis and ImageLinks are kept as they are.
+// PassageIDs that are empty, repeated or already part of the Work are refused.
+// The insertion can be logged, see WithLogger.
+func InsertPassagesAfter(passageID string, passages []Passage, work Work, opts ...Option) (Work, error) {
+	return insertPassages(passageID, true, passages, newOptions(opts), work)
+}
+
+// InsertPassagesBefore inserts a sequence of Passages into a Work before the Passage passageID.
+// An empty passageID inserts them after the last Passage of the Work. See InsertPassagesAfter.
+func InsertPassagesBefore(passageID string, passages []Passage, work Work, opts ...Option) (Work, error) {
+	return insertPassages(passageID, false, passages, newOptions(opts), work)
+}
+
+// SpliceWorkAfter inserts all Passages of other in document order into a Work
+// after the Passage passageID, see InsertPassagesAfter
+func SpliceWorkAfter(passageID string, other, work Work, opts ...Option) (Work, error) {
+	passages, err := documentOrder(other)
+	if err != nil {
+		return work, errors.New("SpliceWorkAfter: " + err.Error())
+	}
+	return insertPassages(passageID, true, passages, newOptions(opts), work)
+}
+
+// SpliceWorkBefore inserts all Passages of other in document order into a Work
+// before the Passage passageID, see InsertPassagesBefore
+func SpliceWorkBefore(passageID string, other, work Work, opts ...Option) (Work, error) {
+	passages, err := documentOrder(other)
+	if err != nil {
+		return work, errors.New("SpliceWorkBefore: " + err.Error())
+	}
+	return insertPassages(passageID, false, passages, newOptions(opts), work)
+}
+
+// insertPassages appends passages to a copy of the Passages slice of a Work
+// and links them in after or before the Passage passageID
+func insertPassages(passageID string, after bool, passages []Passage, o options, work Work) (Work, error) {
+	if len(passages) == 0 {
+		return work, errors.New("insertPassages: no Passages to insert")
+	}
+	seen := make(map[string]bool, len(passages))
+	for _, p := range passages {
+		if p.PassageID == "" {
+			return work, errors.New("insertPassages: Passage without PassageID")
+		}
+		if _, found := GetIndexByID(p.PassageID, work); found || seen[p.PassageID] {
+			return work, errors.New("insertPassages: duplicate PassageID " + p.PassageID)
+		}
+		seen[p.PassageID] = true
+	}
+	prev, next := PassLoc{}, PassLoc{}
+	if len(work.Passages) > 0 {
+		switch {
+		case passageID == "" && after:
+			if !work.First.Exists || !validSlot(work.First.Index, work) {
+				return work, errors.New("insertPassages: First Index not found")
+			}
+			next = work.First
+		case passageID == "":
+			if !work.Last.Exists || !validSlot(work.Last.Index, work) {
+				return work, errors.New("insertPassages: Last Index not found")
+			}
+			prev = work.Last
+		default:
+			index, found := GetIndexByID(passageID, work)
+			if !found {
+				return work, errors.New("insertPassages: Passage " + passageID + " not found in Work " + work.WorkID)
+			}
+			anchor := PassLoc{Exists: true, PassageID: passageID, Index: index}
+			if after {
+				prev, next = anchor, work.Passages[index].Next
+			} else {
+				prev, next = work.Passages[index].Prev, anchor
+			}
+		}
+	} else if passageID != "" {
+		return work, errors.New("insertPassages: Passage " + passageID + " not found in empty Work " + work.WorkID)
+	}
+	ordered := len(work.Passages) == 0 || work.Ordered && !next.Exists
+	offset := len(work.Passages)
+	work.Passages = append(append(make([]Passage, 0, offset+len(passages)), work.Passages...), passages...)
+	for i := offset; i < len(work.Passages); i++ {
+		work.Passages[i].Index = i
+		work.Passages[i].Prev = prev
+		if i > offset {
+			work.Passages[i].Prev = PassLoc{Exists: true, PassageID: work.Passages[i-1].PassageID, Index: i - 1}
+		}
+		work.Passages[i].Next = next
+		if i < len(work.Passages)-1 {
+			work.Passages[i].Next = PassLoc{Exists: true, PassageID: work.Passages[i+1].PassageID, Index: i + 1}
+		}
+	}
+	first := PassLoc{Exists: true, PassageID: work.Passages[offset].PassageID, Index: offset}
+	last := PassLoc{Exists: true, PassageID: work.Passages[len(work.Passages)-1].PassageID, Index: len(work.Passages) - 1}
+	if prev.Exists && validSlot(prev.Index, work) {
+		work.Passages[prev.Index].Next = first
+	} else {
+		work.First = first
+	}
+	if next.Exists && validSlot(next.Index, work) {
+		work.Passages[next.Index].Prev = last
+	} else {
+		work.Last = last
+	}
+	work.Ordered = ordered
+	if o.debug() {
+		indices := make([]int, 0, len(passages))
+		for i := offset; i < len(work.Passages); i++ {
+			indices = append(indices, i)
+		}
+		o.log("inserted Passages", work, passageIDs("passages", indices, work), slog.String("anchor", passageID), slog.Bool("after", after))
+	}
+	ids := make(map[string]int, len(passages))
+	for i := offset; i < len(work.Passages); i++ {
+		ids[work.Passages[i].PassageID] = i
+	}
+	work.idIndex = work.idIndex.with(ids, nil, work.Passages)
+	return buildCitationIndex(work), nil
+}
@@ -0,0 +1,91 @@
+package gocite_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+func newPassages(citations ...string) []gocite.Passage {
+	passages := []gocite.Passage{}
+	for _, citation := range citations {
+		passages = append(passages, gocite.Passage{PassageID: citationWork + citation, Analysis: txtAnalysis("new " + citation)})
+	}
+	return passages
+}
+
+type insertTestgroup struct {
+	anchor   string
+	after    bool
+	expected []string
+	ordered  bool
+}
+
+var insertTests = []insertTestgroup{
+	{anchor: "1.3", after: true, expected: []string{"1.1", "1.2", "1.3", "x.1", "x.2", "2.1", "2.2"}},
+	{anchor: "1.1", after: false, expected: []string{"x.1", "x.2", "1.1", "1.2", "1.3", "2.1", "2.2"}},
+	{anchor: "2.2", after: true, expected: []string{"1.1", "1.2", "1.3", "2.1", "2.2", "x.1", "x.2"}, ordered: true},
+	{anchor: "", after: true, expected: []string{"x.1", "x.2", "1.1", "1.2", "1.3", "2.1", "2.2"}},
+	{anchor: "", after: false, expected: []string{"1.1", "1.2", "1.3", "2.1", "2.2", "x.1", "x.2"}, ordered: true},
+}
+
+func TestInsertPassages(t *testing.T) {
+	for _, test := range insertTests {
+		work := testCitationWork(t)
+		anchor := ""
+		if test.anchor != "" {
+			anchor = citationWork + test.anchor
+		}
+		insert := gocite.InsertPassagesBefore
+		if test.after {
+			insert = gocite.InsertPassagesAfter
+		}
+		result, err := insert(anchor, newPassages("x.1", "x.2"), work)
+		if err != nil {
+			t.Fatal("Error inserting passages: ", err)
+		}
+		checkEdit(t, "insertion at "+test.anchor, work, result, test.expected)
+		if result.Ordered != test.ordered {
+			t.Error("For", test.anchor, test.after, "expected Ordered", test.ordered, "got", result.Ordered)
+		}
+		if text, err := gocite.ExtractTextByID(citationWork+"x.2", result); err != nil || text[0].Text != "new x.2" {
+			t.Error("expected to find x.2, got", text, err)
+		}
+	}
+	work := testCitationWork(t)
+	for _, passages := range [][]gocite.Passage{newPassages("1.2"), newPassages("x.1", "x.1"), {{}}, {}} {
+		if _, err := gocite.InsertPassagesAfter(citationWork+"1.1", passages, work); err == nil {
+			t.Error("expected an error inserting", passages)
+		}
+	}
+	if _, err := gocite.InsertPassagesAfter(citationWork+"3.1", newPassages("x.1"), work); err == nil {
+		t.Error("expected an error for an unknown anchor")
+	}
+	empty, err := gocite.InsertPassagesAfter("", newPassages("x.1", "x.2"), gocite.Work{WorkID: citationWork})
+	if err != nil || !reflect.DeepEqual(documentIDs(t, empty), []string{"x.1", "x.2"}) || !empty.Ordered {
+		t.Error("unexpected insertion into an empty work", empty, err)
+	}
+}
+
+func TestSpliceWork(t *testing.T) {
+	work := testCitationWork(t)
+	folio, err := gocite.DelRange(citationWork+"2", work)
+	if err != nil {
+		t.Fatal("Error calling DelRange: ", err)
+	}
+	edition, err := gocite.DelRange(citationWork+"1", work)
+	if err != nil {
+		t.Fatal("Error calling DelRange: ", err)
+	}
+	result, err := gocite.SpliceWorkBefore(citationWork+"2.1", folio, edition)
+	if err != nil {
+		t.Fatal("Error calling SpliceWorkBefore: ", err)
+	}
+	if ids := documentIDs(t, result); !reflect.DeepEqual(ids, []string{"1.1", "1.2", "1.3", "2.1", "2.2"}) {
+		t.Error("unexpected splice", ids)
+	}
+	if _, err := gocite.SpliceWorkAfter(citationWork+"2.2", work, edition); err == nil {
+		t.Error("expected an error for duplicate PassageIDs")
+	}
+}
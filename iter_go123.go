@@ -0,0 +1,23 @@
+//go:build go1.23
+
+package gocite
+
+import (
+	"iter"
+)
+
+// All returns the Passages the iterator has yet to visit as an iter.Seq for range-over-func loops.
+// Check Err after the loop to learn whether the iteration ended early.
+//
+//	it := NewIterator(work)
+//	for p := range it.All() {
+//	}
+func (it *PassageIterator) All() iter.Seq[Passage] {
+	return func(yield func(Passage) bool) {
+		for it.Next() {
+			if !yield(it.Passage()) {
+				return
+			}
+		}
+	}
+}
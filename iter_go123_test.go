@@ -0,0 +1,26 @@
+//go:build go1.23
+
+package gocite_test
+
+import (
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+func TestIteratorAll(t *testing.T) {
+	it := gocite.NewIterator(testCitationWork(t))
+	count := 0
+	for p := range it.All() {
+		count++
+		if p.PassageID == citationWork+"1.3" {
+			break
+		}
+	}
+	if count != 3 || it.Err() != nil {
+		t.Error("expected to stop at the third passage, got", count, it.Err())
+	}
+	if it.Next(); it.Passage().PassageID != citationWork+"2.1" {
+		t.Error("expected to continue with 2.1, got", it.Passage().PassageID)
+	}
+}
@@ -0,0 +1,112 @@
+package gocite
+
+import (
+	"errors"
+)
+
+// PassageIterator walks the Passages of a Work along their Next links, or their Prev links
+// for a backward iterator, no matter whether the Work is Ordered. It stops with an error
+// when a link points to a missing Passage or back to a Passage it has already visited.
+//
+//	it := NewIterator(work)
+//	for it.Next() {
+//		p := it.Passage()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type PassageIterator struct {
+	work     Work
+	cursor   PassLoc
+	stop     string
+	backward bool
+	visited  map[int]bool
+	current  Passage
+	err      error
+}
+
+// NewIterator returns an iterator over the Passages of a Work in document order, from First to Last
+func NewIterator(work Work) *PassageIterator {
+	it := &PassageIterator{work: work, cursor: work.First, visited: map[int]bool{}}
+	if len(work.Passages) > 0 && !work.First.Exists {
+		it.err = errors.New("First Index not found in Work " + work.WorkID)
+	}
+	return it
+}
+
+// NewReverseIterator returns an iterator over the Passages of a Work in reverse document order, from Last to First
+func NewReverseIterator(work Work) *PassageIterator {
+	it := &PassageIterator{work: work, cursor: work.Last, backward: true, visited: map[int]bool{}}
+	if len(work.Passages) > 0 && !work.Last.Exists {
+		it.err = errors.New("Last Index not found in Work " + work.WorkID)
+	}
+	return it
+}
+
+// NewRangeIterator returns an iterator over the Passages a CTS URN refers to in document order:
+// a single Passage, the Passages of a citation container like 1 or a range like 1.590-2,
+// resolved as by ExtractTextByID, or all Passages for a URN without passage component.
+// Subreferences are ignored, the Passages they are part of are visited as a whole.
+func NewRangeIterator(ctsID string, work Work) (*PassageIterator, error) {
+	urn := SplitCTS(ctsID)
+	if urn.InValid {
+		return nil, errors.New("NewRangeIterator: urn is not a valid cts urn")
+	}
+	if urn.Passage == "" {
+		return NewIterator(work), nil
+	}
+	end := urn.Start
+	if urn.Range {
+		end = urn.End
+	}
+	startID, err := rangeBound(urn, urn.Start, true, work)
+	if err != nil {
+		return nil, errors.New("NewRangeIterator: " + err.Error())
+	}
+	endID, err := rangeBound(urn, end, false, work)
+	if err != nil {
+		return nil, errors.New("NewRangeIterator: " + err.Error())
+	}
+	index, _ := GetIndexByID(startID, work)
+	return &PassageIterator{work: work, cursor: PassLoc{Exists: true, PassageID: startID, Index: index}, stop: endID, visited: map[int]bool{}}, nil
+}
+
+// Next advances the iterator to the next Passage, which is then available from Passage.
+// It returns false when there are no more Passages or an error occurred, see Err.
+func (it *PassageIterator) Next() bool {
+	if it.err != nil || !it.cursor.Exists {
+		return false
+	}
+	index := it.cursor.Index
+	switch {
+	case !validSlot(index, it.work):
+		it.err = errors.New("Passage " + it.cursor.PassageID + " not found in Work " + it.work.WorkID)
+		return false
+	case it.visited[index]:
+		it.err = errors.New("work is loopy: " + it.work.WorkID)
+		return false
+	}
+	it.visited[index] = true
+	it.current = it.work.Passages[index]
+	switch {
+	case it.stop != "" && it.current.PassageID == it.stop:
+		it.cursor = PassLoc{}
+	case it.backward:
+		it.cursor = it.current.Prev
+	default:
+		it.cursor = it.current.Next
+	}
+	if it.stop != "" && !it.cursor.Exists && it.current.PassageID != it.stop {
+		it.err = errors.New("unexpected end of work")
+	}
+	return true
+}
+
+// Passage returns the Passage the iterator is at
+func (it *PassageIterator) Passage() Passage {
+	return it.current
+}
+
+// Err returns the error that stopped the iterator, nil if it ran through all Passages
+func (it *PassageIterator) Err() error {
+	return it.err
+}
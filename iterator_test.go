@@ -0,0 +1,62 @@
+package gocite_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+func iteratedIDs(it *gocite.PassageIterator) []string {
+	ids := []string{}
+	for it.Next() {
+		ids = append(ids, strings.TrimPrefix(it.Passage().PassageID, citationWork))
+	}
+	return ids
+}
+
+func TestIterator(t *testing.T) {
+	work, err := gocite.MovePassage(citationWork+"1.1", citationWork+"1.2", testCitationWork(t))
+	if err != nil {
+		t.Fatal("Error calling MovePassage: ", err)
+	}
+	it := gocite.NewIterator(work)
+	if ids := iteratedIDs(it); !reflect.DeepEqual(ids, []string{"1.2", "1.1", "1.3", "2.1", "2.2"}) || it.Err() != nil {
+		t.Error("unexpected forward iteration", ids, it.Err())
+	}
+	it = gocite.NewReverseIterator(work)
+	if ids := iteratedIDs(it); !reflect.DeepEqual(ids, []string{"2.2", "2.1", "1.3", "1.1", "1.2"}) || it.Err() != nil {
+		t.Error("unexpected backward iteration", ids, it.Err())
+	}
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "1", expected: []string{"1.2", "1.1", "1.3"}},
+		{input: "1.1-2.1", expected: []string{"1.1", "1.3", "2.1"}},
+		{input: "1.3-2", expected: []string{"1.3", "2.1", "2.2"}},
+		{input: "2.1@θεοί", expected: []string{"2.1"}},
+	}
+	for _, test := range tests {
+		it, err := gocite.NewRangeIterator(citationWork+test.input, work)
+		if err != nil {
+			t.Fatal("Error calling NewRangeIterator: ", err)
+		}
+		if ids := iteratedIDs(it); !reflect.DeepEqual(ids, test.expected) || it.Err() != nil {
+			t.Error("For", test.input, "expected", test.expected, "got", ids, it.Err())
+		}
+	}
+	if _, err := gocite.NewRangeIterator(citationWork+"3", work); err == nil {
+		t.Error("expected an error for book 3")
+	}
+	it, _ = gocite.NewRangeIterator(citationWork+"2.1-1.3", work)
+	if iteratedIDs(it); it.Err() == nil {
+		t.Error("expected an error for a range ending before it starts")
+	}
+	work.Passages[4].Next = gocite.PassLoc{Exists: true, PassageID: work.Passages[0].PassageID, Index: 0}
+	it = gocite.NewIterator(work)
+	if ids := iteratedIDs(it); len(ids) != 5 || it.Err() == nil {
+		t.Error("expected the iteration to stop at the cycle, got", ids, it.Err())
+	}
+}
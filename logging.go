@@ -0,0 +1,64 @@
+package gocite
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Option configures optional behaviour of SortPassages and the functions changing Works,
+// e.g. DelPassage, InsertPassagesAfter or MovePassage
+type Option func(*options)
+
+// options holds the configuration built from a list of Options
+type options struct {
+	logger *slog.Logger
+	ctx    context.Context
+}
+
+// WithLogger makes a function report what it does to logger, at debug level and with
+// the WorkID and PassageIDs as structured fields. Without it, nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithContext passes ctx to the logger given by WithLogger, e.g. for request-scoped values
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// newOptions applies opts to the default configuration, which does not log
+func newOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.ctx == nil {
+		o.ctx = context.Background()
+	}
+	return o
+}
+
+// debug tests whether debug messages are logged, so that callers only build their fields if they are
+func (o options) debug() bool {
+	return o.logger != nil && o.logger.Enabled(o.ctx, slog.LevelDebug)
+}
+
+// log logs msg for a Work at debug level, callers check debug first
+func (o options) log(msg string, work Work, attrs ...slog.Attr) {
+	o.logger.LogAttrs(o.ctx, slog.LevelDebug, msg, append([]slog.Attr{slog.String("work", work.WorkID)}, attrs...)...)
+}
+
+// passageIDs returns the PassageIDs of the Passages at the given slice indices as a log field
+func passageIDs(key string, indices []int, work Work) slog.Attr {
+	ids := make([]string, 0, len(indices))
+	for _, index := range indices {
+		if index >= 0 && index < len(work.Passages) {
+			ids = append(ids, work.Passages[index].PassageID)
+		}
+	}
+	return slog.Any(key, ids)
+}
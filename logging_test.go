@@ -0,0 +1,59 @@
+package gocite_test
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+func TestWithLogger(t *testing.T) {
+	var global bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&global)
+	var debug, info bytes.Buffer
+	debugLogger := slog.New(slog.NewTextHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	infoLogger := slog.New(slog.NewTextHandler(&info, nil))
+	work := testCitationWork(t)
+	if _, err := gocite.SortPassages(work); err != nil {
+		t.Fatal("Error calling SortPassages: ", err)
+	}
+	if _, err := gocite.SortPassages(work, gocite.WithLogger(infoLogger)); err != nil {
+		t.Fatal("Error calling SortPassages: ", err)
+	}
+	work, err := gocite.SortPassages(work, gocite.WithLogger(debugLogger))
+	if err != nil {
+		t.Fatal("Error calling SortPassages: ", err)
+	}
+	if _, err := gocite.DelRange(citationWork+"2", work, gocite.WithLogger(debugLogger)); err != nil {
+		t.Fatal("Error calling DelRange: ", err)
+	}
+	if global.Len() != 0 || info.Len() != 0 {
+		t.Error("expected no log output without a debug logger, got", global.String(), info.String())
+	}
+	for _, want := range []string{
+		"msg=\"sorting Passages\" work=" + citationWork + " passages=5",
+		"msg=\"moving Passage\" work=" + citationWork + " passage=" + citationWork + "1.2 from=1 to=1",
+		"msg=\"deleting Passages\" work=" + citationWork + " passages=\"[" + citationWork + "2.1 " + citationWork + "2.2]\"",
+	} {
+		if !strings.Contains(debug.String(), want) {
+			t.Errorf("expected %q in log output\n%s", want, debug.String())
+		}
+	}
+}
+
+func TestOptionsWithoutLogger(t *testing.T) {
+	work := testCitationWork(t)
+	plain := testing.AllocsPerRun(100, func() {
+		gocite.MovePassage(citationWork+"2.1", "", work)
+	})
+	quiet := testing.AllocsPerRun(100, func() {
+		gocite.MovePassage(citationWork+"2.1", "", work, gocite.WithLogger(nil))
+	})
+	if quiet > plain+1 {
+		t.Errorf("expected no overhead without a logger, got %v allocations instead of %v", quiet, plain)
+	}
+}
@@ -0,0 +1,345 @@
+// Package microservice serves texts, CITE collections and relations as JSON,
+// modelled on the endpoints of the CITE microservices:
+//
+//	/texts/{urn}            text of a passage, range or subreference
+//	/texts/first/{urn}      first passage of a work
+//	/texts/next/{urn}       passage following a passage
+//	/texts/prev/{urn}       passage preceding a passage
+//	/texts/reff/{urn}       URNs of the passages a URN refers to
+//	/collections/{urn}      definitions of CITE collections
+//	/objects/{urn}          objects of CITE collections
+//	/relations/{urn}        relations a CTS or CITE2 URN takes part in
+package microservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ThomasK81/gocite"
+)
+
+// Handler is an http.Handler serving the texts, collections and relations of a corpus as JSON
+type Handler struct {
+	Repository  gocite.TextRepository
+	Collections []gocite.CiteCollection
+	Relations   []gocite.Triple
+	mux         *http.ServeMux
+}
+
+// NewHandler returns a Handler serving the texts, collections and relations of CEXData.
+// The ImageLinks of all Passages are served as relations along with data.Relations.
+func NewHandler(data gocite.CEXData) *Handler {
+	h := &Handler{Repository: gocite.NewTextRepository(data), Collections: data.Collections}
+	h.Relations = append(h.Relations, data.Relations...)
+	for _, group := range data.Textgroups {
+		for _, work := range group.Works {
+			for _, p := range work.Passages {
+				h.Relations = append(h.Relations, p.ImageLinks...)
+			}
+		}
+	}
+	h.mux = http.NewServeMux()
+	h.mux.HandleFunc("/texts/", h.serveTexts)
+	h.mux.HandleFunc("/texts/first/", h.serveFirst)
+	h.mux.HandleFunc("/texts/next/", h.serveNext)
+	h.mux.HandleFunc("/texts/prev/", h.servePrev)
+	h.mux.HandleFunc("/texts/reff/", h.serveReff)
+	h.mux.HandleFunc("/collections/", h.serveCollections)
+	h.mux.HandleFunc("/objects/", h.serveObjects)
+	h.mux.HandleFunc("/relations/", h.serveRelations)
+	return h
+}
+
+// CitableNode is a passage of text identified by its CTS URN
+type CitableNode struct {
+	URN  string `json:"urn"`
+	Text string `json:"text"`
+}
+
+// TextResponse is returned by the /texts endpoints
+type TextResponse struct {
+	RequestURN   string        `json:"requestUrn"`
+	CitableNodes []CitableNode `json:"citableNodes"`
+}
+
+// ReffResponse is returned by /texts/reff
+type ReffResponse struct {
+	RequestURN string   `json:"requestUrn"`
+	URNs       []string `json:"urns"`
+}
+
+// Collection is the definition of a CITE collection and its properties
+type Collection struct {
+	URN               string     `json:"urn"`
+	Description       string     `json:"collectionLabel"`
+	LabellingProperty string     `json:"labellingProperty"`
+	OrderingProperty  string     `json:"orderingProperty,omitempty"`
+	License           string     `json:"license"`
+	Properties        []Property `json:"propertyDefs"`
+}
+
+// Property is the definition of a property of a CITE collection
+type Property struct {
+	URN       string   `json:"urn"`
+	Label     string   `json:"label"`
+	Type      string   `json:"propertyType"`
+	Authority []string `json:"vocabularyList,omitempty"`
+}
+
+// CollectionResponse is returned by /collections
+type CollectionResponse struct {
+	RequestURN  string       `json:"requestUrn"`
+	Collections []Collection `json:"citeCollectionDefs"`
+}
+
+// Object is an object of a CITE collection with its property values
+type Object struct {
+	URN        string            `json:"urn"`
+	Properties map[string]string `json:"propertyValues"`
+}
+
+// ObjectResponse is returned by /objects
+type ObjectResponse struct {
+	RequestURN string   `json:"requestUrn"`
+	Objects    []Object `json:"citeObjects"`
+}
+
+// Relation is a relation between two URNs
+type Relation struct {
+	URN1     string `json:"urn1"`
+	Relation string `json:"relation"`
+	URN2     string `json:"urn2"`
+}
+
+// RelationResponse is returned by /relations
+type RelationResponse struct {
+	RequestURN string     `json:"requestUrn"`
+	Relations  []Relation `json:"citeTriples"`
+}
+
+// ErrorResponse is returned with all error status codes
+type ErrorResponse struct {
+	RequestURN string `json:"requestUrn"`
+	Error      string `json:"error"`
+}
+
+// ServeHTTP answers a request to one of the endpoints
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// serveTexts answers /texts/{urn} the way ExtractTextByID resolves ranges and subreferences
+func (h *Handler) serveTexts(w http.ResponseWriter, r *http.Request) {
+	urn, ok := ctsParam(w, r, "/texts/")
+	if !ok {
+		return
+	}
+	texts, err := h.Repository.ExtractTextByID(urn)
+	if err != nil {
+		writeError(w, http.StatusNotFound, urn, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, TextResponse{RequestURN: urn, CitableNodes: citableNodes(texts)})
+}
+
+// serveFirst answers /texts/first/{urn} with the first Passage of the Work
+func (h *Handler) serveFirst(w http.ResponseWriter, r *http.Request) {
+	urn, ok := ctsParam(w, r, "/texts/first/")
+	if !ok {
+		return
+	}
+	work, err := h.Repository.FindWork(urn)
+	if err != nil {
+		writeError(w, http.StatusNotFound, urn, err)
+		return
+	}
+	nodes := []CitableNode{}
+	if index, found := gocite.GetFirstIndex(work); found && index < len(work.Passages) {
+		nodes = append(nodes, citableNode(work.Passages[index]))
+	}
+	writeJSON(w, http.StatusOK, TextResponse{RequestURN: urn, CitableNodes: nodes})
+}
+
+// serveNext answers /texts/next/{urn} with the Passage following the Passage urn refers to
+func (h *Handler) serveNext(w http.ResponseWriter, r *http.Request) {
+	h.serveNeighbour(w, r, "/texts/next/", func(p gocite.Passage) gocite.PassLoc { return p.Next })
+}
+
+// servePrev answers /texts/prev/{urn} with the Passage preceding the Passage urn refers to
+func (h *Handler) servePrev(w http.ResponseWriter, r *http.Request) {
+	h.serveNeighbour(w, r, "/texts/prev/", func(p gocite.Passage) gocite.PassLoc { return p.Prev })
+}
+
+// serveNeighbour answers with the Passage linked to the Passage urn refers to.
+// The list of citable nodes is empty at the start or end of a Work.
+func (h *Handler) serveNeighbour(w http.ResponseWriter, r *http.Request, prefix string, link func(gocite.Passage) gocite.PassLoc) {
+	urn, ok := ctsParam(w, r, prefix)
+	if !ok {
+		return
+	}
+	if parsed := gocite.SplitCTS(urn); parsed.Passage == "" || parsed.Range {
+		writeError(w, http.StatusBadRequest, urn, errors.New("urn must refer to a single passage"))
+		return
+	}
+	work, resolved, err := h.Repository.ResolveURN(urn)
+	if err != nil {
+		writeError(w, http.StatusNotFound, urn, err)
+		return
+	}
+	p, err := gocite.GetPassageByID(gocite.SplitCTS(resolved).DropSubref().ID, work)
+	if err != nil {
+		writeError(w, http.StatusNotFound, urn, err)
+		return
+	}
+	nodes := []CitableNode{}
+	if loc := link(p); loc.Exists {
+		if neighbour, err := gocite.GetPassageByID(loc.PassageID, work); err == nil {
+			nodes = append(nodes, citableNode(neighbour))
+		}
+	}
+	writeJSON(w, http.StatusOK, TextResponse{RequestURN: urn, CitableNodes: nodes})
+}
+
+// serveReff answers /texts/reff/{urn} with the URNs of the Passages urn refers to
+func (h *Handler) serveReff(w http.ResponseWriter, r *http.Request) {
+	urn, ok := ctsParam(w, r, "/texts/reff/")
+	if !ok {
+		return
+	}
+	passages, err := h.Repository.GetPassages(urn)
+	if err != nil {
+		writeError(w, http.StatusNotFound, urn, err)
+		return
+	}
+	urns := []string{}
+	for _, p := range passages {
+		urns = append(urns, p.PassageID)
+	}
+	writeJSON(w, http.StatusOK, ReffResponse{RequestURN: urn, URNs: urns})
+}
+
+// serveCollections answers /collections/{urn} with the definitions of all collections matching urn
+func (h *Handler) serveCollections(w http.ResponseWriter, r *http.Request) {
+	urn, parsed, ok := citeParam(w, r, "/collections/")
+	if !ok {
+		return
+	}
+	collections := []Collection{}
+	for _, coll := range h.Collections {
+		if !gocite.SplitCITE(coll.URN).MatchIgnoringVersion(parsed.DropObject()) {
+			continue
+		}
+		result := Collection{URN: coll.URN, Description: coll.Description, LabellingProperty: coll.LabellingProperty,
+			OrderingProperty: coll.OrderingProperty, License: coll.License, Properties: []Property{}}
+		for _, property := range coll.Properties {
+			result.Properties = append(result.Properties, Property{URN: property.URN, Label: property.Label,
+				Type: property.Type, Authority: property.Authority})
+		}
+		collections = append(collections, result)
+	}
+	if len(collections) == 0 {
+		writeError(w, http.StatusNotFound, urn, errors.New("no collection found for "+urn))
+		return
+	}
+	writeJSON(w, http.StatusOK, CollectionResponse{RequestURN: urn, Collections: collections})
+}
+
+// serveObjects answers /objects/{urn} with all objects contained by urn
+func (h *Handler) serveObjects(w http.ResponseWriter, r *http.Request) {
+	urn, parsed, ok := citeParam(w, r, "/objects/")
+	if !ok {
+		return
+	}
+	objects := []Object{}
+	for _, coll := range h.Collections {
+		for _, object := range coll.Objects {
+			if gocite.SplitCITE(object.URN).IsContainedBy(parsed.DropProperty()) {
+				objects = append(objects, Object{URN: object.URN, Properties: object.Properties})
+			}
+		}
+	}
+	if len(objects) == 0 {
+		writeError(w, http.StatusNotFound, urn, errors.New("no object found for "+urn))
+		return
+	}
+	writeJSON(w, http.StatusOK, ObjectResponse{RequestURN: urn, Objects: objects})
+}
+
+// serveRelations answers /relations/{urn} with all relations whose subject or object matches urn.
+// The list of relations is empty if urn takes part in none.
+func (h *Handler) serveRelations(w http.ResponseWriter, r *http.Request) {
+	urn := strings.TrimPrefix(r.URL.Path, "/relations/")
+	var match func(string) bool
+	switch {
+	case gocite.IsCTSURN(urn):
+		parsed := gocite.SplitCTS(urn)
+		match = func(s string) bool { return gocite.IsCTSURN(s) && gocite.SplitCTS(s).UrnMatch(parsed) }
+	case gocite.IsCITEURN(urn):
+		parsed := gocite.SplitCITE(urn)
+		match = func(s string) bool { return gocite.IsCITEURN(s) && gocite.SplitCITE(s).UrnMatch(parsed) }
+	default:
+		writeError(w, http.StatusBadRequest, urn, errors.New("not a valid CTS or CITE2 urn"))
+		return
+	}
+	relations := []Relation{}
+	for _, triple := range h.Relations {
+		if match(triple.Subject) || match(triple.Object) {
+			relations = append(relations, Relation{URN1: triple.Subject, Relation: triple.Verb, URN2: triple.Object})
+		}
+	}
+	writeJSON(w, http.StatusOK, RelationResponse{RequestURN: urn, Relations: relations})
+}
+
+// ctsParam returns the CTS URN following prefix in the request path
+// and answers with 400 Bad Request if it is not a valid CTS URN
+func ctsParam(w http.ResponseWriter, r *http.Request, prefix string) (string, bool) {
+	urn := strings.TrimPrefix(r.URL.Path, prefix)
+	if _, err := gocite.ParseCTSURN(urn); err != nil {
+		writeError(w, http.StatusBadRequest, urn, err)
+		return urn, false
+	}
+	return urn, true
+}
+
+// citeParam returns the CITE2 URN following prefix in the request path
+// and answers with 400 Bad Request if it is not a valid CITE2 URN
+func citeParam(w http.ResponseWriter, r *http.Request, prefix string) (string, gocite.Cite2Urn, bool) {
+	urn := strings.TrimPrefix(r.URL.Path, prefix)
+	parsed, err := gocite.ParseCITEURN(urn)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, urn, err)
+		return urn, parsed, false
+	}
+	return urn, parsed, true
+}
+
+func citableNodes(texts []gocite.TextAndID) []CitableNode {
+	nodes := []CitableNode{}
+	for _, text := range texts {
+		nodes = append(nodes, CitableNode{URN: text.ID, Text: text.Text})
+	}
+	return nodes
+}
+
+func citableNode(p gocite.Passage) CitableNode {
+	text, _ := gocite.PassageText(p)
+	return CitableNode{URN: p.PassageID, Text: text}
+}
+
+func writeError(w http.ResponseWriter, status int, urn string, err error) {
+	writeJSON(w, status, ErrorResponse{RequestURN: urn, Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "%s\n", body)
+}
@@ -0,0 +1,136 @@
+package microservice_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+	"github.com/ThomasK81/gocite/microservice"
+)
+
+var testCEX = `#!ctsdata
+urn:cts:greekLit:tlg0012.tlg001.msA:1.1#Μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος
+urn:cts:greekLit:tlg0012.tlg001.msA:1.2#οὐλομένην, ἣ μυρί' Ἀχαιοῖς ἄλγε' ἔθηκε,
+urn:cts:greekLit:tlg0012.tlg001.msA:1.3#πολλὰς δ' ἰφθίμους ψυχὰς Ἄϊδι προΐαψεν
+
+#!citecollections
+URN#Description#Labelling property#Ordering property#License
+urn:cite2:hmt:msA.v1:#Pages of the Venetus A#urn:cite2:hmt:msA.v1.label:#urn:cite2:hmt:msA.v1.sequence:#CC BY 3.0
+
+#!citeproperties
+Property#Label#Type#Authority list
+urn:cite2:hmt:msA.v1.urn:#URN#Cite2Urn#
+urn:cite2:hmt:msA.v1.rv:#Recto or Verso#String#recto,verso
+
+#!citedata
+urn#sequence#rv
+urn:cite2:hmt:msA.v1:1r#1#recto
+urn:cite2:hmt:msA.v1:1v#2#verso
+
+#!relations
+urn:cts:greekLit:tlg0012.tlg001.msA:1.1#urn:cite2:cite:verbs.v1:illustratedBy#urn:cite2:hmt:vaimg.2017a:VA012RN_0013@0.1,0.2,0.3,0.4
+urn:cts:greekLit:tlg0012.tlg001.msA:1.3#urn:cite2:cite:verbs.v1:illustratedBy#urn:cite2:hmt:vaimg.2017a:VA012RN_0013@0.5,0.6,0.3,0.4
+`
+
+func testHandler(t *testing.T) *microservice.Handler {
+	data, err := gocite.ReadCEX(strings.NewReader(testCEX))
+	if err != nil {
+		t.Fatal("Error calling ReadCEX: ", err)
+	}
+	return microservice.NewHandler(data)
+}
+
+// get requests path from handler and decodes the JSON response into result
+func get(t *testing.T, handler http.Handler, path string, result interface{}) int {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+	if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+		t.Fatal("Error decoding response for", path, err, recorder.Body.String())
+	}
+	return recorder.Code
+}
+
+func TestTexts(t *testing.T) {
+	handler := testHandler(t)
+	work := "urn:cts:greekLit:tlg0012.tlg001.msA:"
+	tests := []struct {
+		path     string
+		expected []microservice.CitableNode
+	}{
+		{path: "/texts/" + work + "1.2-1.3", expected: []microservice.CitableNode{
+			{URN: work + "1.2", Text: "οὐλομένην, ἣ μυρί' Ἀχαιοῖς ἄλγε' ἔθηκε,"},
+			{URN: work + "1.3", Text: "πολλὰς δ' ἰφθίμους ψυχὰς Ἄϊδι προΐαψεν"}}},
+		{path: "/texts/" + work + "1.1@ἄειδε", expected: []microservice.CitableNode{{URN: work + "1.1@ἄειδε", Text: "ἄειδε"}}},
+		{path: "/texts/first/urn:cts:greekLit:tlg0012.tlg001:", expected: []microservice.CitableNode{
+			{URN: work + "1.1", Text: "Μῆνιν ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος"}}},
+		{path: "/texts/next/" + work + "1.2", expected: []microservice.CitableNode{
+			{URN: work + "1.3", Text: "πολλὰς δ' ἰφθίμους ψυχὰς Ἄϊδι προΐαψεν"}}},
+		{path: "/texts/prev/" + work + "1.1", expected: []microservice.CitableNode{}},
+	}
+	for _, test := range tests {
+		var result microservice.TextResponse
+		if status := get(t, handler, test.path, &result); status != http.StatusOK {
+			t.Error("For", test.path, "expected status 200, got", status)
+		}
+		if !reflect.DeepEqual(result.CitableNodes, test.expected) {
+			t.Error("For", test.path, "expected", test.expected, "got", result.CitableNodes)
+		}
+	}
+	var reff microservice.ReffResponse
+	get(t, handler, "/texts/reff/urn:cts:greekLit:tlg0012.tlg001:1", &reff)
+	if !reflect.DeepEqual(reff.URNs, []string{work + "1.1", work + "1.2", work + "1.3"}) {
+		t.Error("unexpected reff", reff)
+	}
+}
+
+func TestCollections(t *testing.T) {
+	handler := testHandler(t)
+	var collections microservice.CollectionResponse
+	get(t, handler, "/collections/urn:cite2:hmt:msA:", &collections)
+	if len(collections.Collections) != 1 || len(collections.Collections[0].Properties) != 2 ||
+		collections.Collections[0].Description != "Pages of the Venetus A" {
+		t.Error("unexpected collections", collections)
+	}
+	var objects microservice.ObjectResponse
+	get(t, handler, "/objects/urn:cite2:hmt:msA.v1:1v", &objects)
+	expected := []microservice.Object{{URN: "urn:cite2:hmt:msA.v1:1v", Properties: map[string]string{"urn": "urn:cite2:hmt:msA.v1:1v", "sequence": "2", "rv": "verso"}}}
+	if !reflect.DeepEqual(objects.Objects, expected) {
+		t.Error("expected", expected, "got", objects.Objects)
+	}
+	var relations microservice.RelationResponse
+	get(t, handler, "/relations/urn:cite2:hmt:vaimg.2017a:VA012RN_0013", &relations)
+	if len(relations.Relations) != 2 {
+		t.Error("expected 2 relations, got", relations)
+	}
+	get(t, handler, "/relations/urn:cts:greekLit:tlg0012.tlg001:1.3", &relations)
+	if len(relations.Relations) != 1 || relations.Relations[0].URN1 != "urn:cts:greekLit:tlg0012.tlg001.msA:1.3" {
+		t.Error("expected the relation of 1.3, got", relations)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	handler := testHandler(t)
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{path: "/texts/urn:cts:greekLit:tlg0012.tlg001.msA", status: http.StatusBadRequest},
+		{path: "/texts/urn:cts:greekLit:tlg0012.tlg001.msA:9.9", status: http.StatusNotFound},
+		{path: "/texts/urn:cts:greekLit:tlg0012.tlg001.msA:1.1@missing", status: http.StatusNotFound},
+		{path: "/texts/next/urn:cts:greekLit:tlg0012.tlg001.msA:1.1-1.2", status: http.StatusBadRequest},
+		{path: "/texts/first/urn:cts:latinLit:phi0959.phi006:", status: http.StatusNotFound},
+		{path: "/objects/urn:cite2:hmt:msA.v1:9r", status: http.StatusNotFound},
+		{path: "/collections/urn:cts:greekLit:tlg0012.tlg001:", status: http.StatusBadRequest},
+		{path: "/relations/not-a-urn", status: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		var result microservice.ErrorResponse
+		if status := get(t, handler, test.path, &result); status != test.status || result.Error == "" {
+			t.Error("For", test.path, "expected", test.status, "got", status, result)
+		}
+	}
+}
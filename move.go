@@ -0,0 +1,107 @@
+package gocite
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// MovePassage moves the Passage passageID behind the Passage afterID, or to the beginning of
+// the Work if afterID is empty, by relinking Prev and Next. The Passage keeps its Analysis and ImageLinks,
+// First and Last are updated. The Work is marked as Ordered if its document order matches
+// the order of the Passages slice after the move, see SortPassages.
+// The move can be logged, see WithLogger.
+func MovePassage(passageID, afterID string, work Work, opts ...Option) (Work, error) {
+	index, found := GetIndexByID(passageID, work)
+	if !found {
+		return work, errors.New("MovePassage: Passage " + passageID + " not found in Work " + work.WorkID)
+	}
+	return moveBlock([]int{index}, afterID, newOptions(opts), work)
+}
+
+// MoveRange moves all Passages a CTS URN refers to, e.g. a range like 1.5-1.20 or a container like 1,
+// as one block behind the Passage afterID, see MovePassage
+func MoveRange(ctsID, afterID string, work Work, opts ...Option) (Work, error) {
+	urn := SplitCTS(ctsID)
+	if urn.InValid || urn.Passage == "" || urn.Start.Subref.Exists || urn.End.Subref.Exists {
+		return work, errors.New("MoveRange: " + ctsID + " does not refer to whole Passages")
+	}
+	passages, err := passagesByID(ctsID, work)
+	if err != nil {
+		return work, errors.New("MoveRange: " + err.Error())
+	}
+	indices := []int{}
+	for _, p := range passages {
+		index, _ := GetIndexByID(p.PassageID, work)
+		indices = append(indices, index)
+	}
+	return moveBlock(indices, afterID, newOptions(opts), work)
+}
+
+// moveBlock moves the Passages at the given slice indices, which must follow each other
+// in document order, behind the Passage afterID
+func moveBlock(indices []int, afterID string, o options, work Work) (Work, error) {
+	block := make(map[int]bool, len(indices))
+	for i, index := range indices {
+		block[index] = true
+		if i > 0 && work.Passages[indices[i-1]].Next.Index != index {
+			return work, errors.New("moveBlock: Passages to move do not follow each other")
+		}
+	}
+	head, tail := indices[0], indices[len(indices)-1]
+	anchor := PassLoc{}
+	if afterID != "" {
+		index, found := GetIndexByID(afterID, work)
+		if !found {
+			return work, errors.New("moveBlock: Passage " + afterID + " not found in Work " + work.WorkID)
+		}
+		if block[index] {
+			return work, errors.New("moveBlock: cannot move Passages behind " + afterID + ", which is moved itself")
+		}
+		anchor = PassLoc{Exists: true, PassageID: afterID, Index: index}
+	}
+	if o.debug() {
+		o.log("moving Passages", work, passageIDs("passages", indices, work), slog.String("after", afterID))
+	}
+	work = ownPassages(work)
+	headLoc := PassLoc{Exists: true, PassageID: work.Passages[head].PassageID, Index: head}
+	tailLoc := PassLoc{Exists: true, PassageID: work.Passages[tail].PassageID, Index: tail}
+	prev, next := work.Passages[head].Prev, work.Passages[tail].Next
+	if prev.Exists && validSlot(prev.Index, work) {
+		work.Passages[prev.Index].Next = next
+	} else {
+		work.First = next
+	}
+	if next.Exists && validSlot(next.Index, work) {
+		work.Passages[next.Index].Prev = prev
+	} else {
+		work.Last = prev
+	}
+	after := work.First
+	if anchor.Exists {
+		after = work.Passages[anchor.Index].Next
+		work.Passages[anchor.Index].Next = headLoc
+	} else {
+		work.First = headLoc
+	}
+	work.Passages[head].Prev = anchor
+	work.Passages[tail].Next = after
+	if after.Exists && validSlot(after.Index, work) {
+		work.Passages[after.Index].Prev = tailLoc
+	} else {
+		work.Last = tailLoc
+	}
+	work.Ordered = inSliceOrder(work)
+	return buildCitationIndex(work), nil
+}
+
+// inSliceOrder tests whether the document order of a Work is the order of its Passages slice
+func inSliceOrder(work Work) bool {
+	cursor := work.First
+	for i := range work.Passages {
+		if !cursor.Exists || cursor.Index != i {
+			return false
+		}
+		cursor = work.Passages[i].Next
+	}
+	return !cursor.Exists
+}
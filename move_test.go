@@ -0,0 +1,67 @@
+package gocite_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+type moveTestgroup struct {
+	passage, after string
+	expected       []string
+	ordered        bool
+}
+
+var moveTests = []moveTestgroup{
+	{passage: "1.1", after: "1.3", expected: []string{"1.2", "1.3", "1.1", "2.1", "2.2"}},
+	{passage: "2.2", after: "", expected: []string{"2.2", "1.1", "1.2", "1.3", "2.1"}},
+	{passage: "1.2", after: "2.2", expected: []string{"1.1", "1.3", "2.1", "2.2", "1.2"}},
+	{passage: "1.2", after: "1.1", expected: []string{"1.1", "1.2", "1.3", "2.1", "2.2"}, ordered: true},
+}
+
+func TestMovePassage(t *testing.T) {
+	for _, test := range moveTests {
+		work := testCitationWork(t)
+		after := ""
+		if test.after != "" {
+			after = citationWork + test.after
+		}
+		result, err := gocite.MovePassage(citationWork+test.passage, after, work)
+		if err != nil {
+			t.Fatal("Error calling MovePassage: ", err)
+		}
+		checkEdit(t, "moving "+test.passage+" after "+test.after, work, result, test.expected)
+		if result.Ordered != test.ordered {
+			t.Error("For", test.passage, test.after, "expected Ordered", test.ordered, "got", result.Ordered)
+		}
+	}
+	if _, err := gocite.MovePassage(citationWork+"1.1", citationWork+"1.1", testCitationWork(t)); err == nil {
+		t.Error("expected an error moving a passage behind itself")
+	}
+}
+
+func TestMoveRange(t *testing.T) {
+	work := testCitationWork(t)
+	moved, err := gocite.MoveRange(citationWork+"1", citationWork+"2.2", work)
+	if err != nil {
+		t.Fatal("Error calling MoveRange: ", err)
+	}
+	if ids := documentIDs(t, moved); !reflect.DeepEqual(ids, []string{"2.1", "2.2", "1.1", "1.2", "1.3"}) || moved.Ordered {
+		t.Error("unexpected order after moving book 1", ids, moved.Ordered)
+	}
+	if text, err := gocite.ExtractTextByID(citationWork+"2.2-1.1", moved); err != nil || len(text) != 2 {
+		t.Error("expected to extract across the moved block, got", text, err)
+	}
+	restored, err := gocite.MoveRange(citationWork+"1.1-1.3", "", moved)
+	if err != nil || !restored.Ordered || !gocite.ValidateWork(restored).Valid() {
+		t.Error("expected the original order to be restored, got", documentIDs(t, restored), err)
+	}
+	textless, err := gocite.MoveRange(citationWork+"1.2-1.3", citationWork+"2.2", textlessWork(t))
+	if ids := documentIDs(t, textless); err != nil || !reflect.DeepEqual(ids, []string{"1.1", "2.1", "2.2", "1.2", "1.3"}) {
+		t.Error("unexpected order after moving passages without text", ids, err)
+	}
+	if _, err := gocite.MoveRange(citationWork+"1.2-2.1", citationWork+"1.3", work); err == nil {
+		t.Error("expected an error moving a range behind one of its passages")
+	}
+}
@@ -0,0 +1,232 @@
+package gocite
+
+import (
+	"errors"
+	"strings"
+)
+
+// TextRepository holds the Textgroups and Works of a corpus together with their
+// CTS catalog metadata and resolves CTS URNs at any level of the work hierarchy
+// to the Works and Passages they refer to.
+type TextRepository struct {
+	Catalog    []CatalogEntry
+	Textgroups []Textgroup
+}
+
+// NewTextRepository builds a TextRepository from the catalog and the texts of CEXData
+func NewTextRepository(data CEXData) TextRepository {
+	return TextRepository{Catalog: data.Catalog, Textgroups: data.Textgroups}
+}
+
+// AddWork adds a Work to the Textgroup it belongs to, adding the Textgroup if necessary,
+// and records its CatalogEntry if the catalog has none for the Work yet.
+// A Work without CitationScheme takes it from the catalog.
+// A Work with the same WorkID must not already be in the repository.
+func (repo *TextRepository) AddWork(work Work, entry CatalogEntry) error {
+	urn, err := ParseCTSURN(work.WorkID)
+	if err != nil {
+		return err
+	}
+	if _, found := repo.getWork(work.WorkID); found {
+		return errors.New("AddWork: Work " + work.WorkID + " already in repository")
+	}
+	groupID := textgroupID(urn)
+	index := -1
+	for i := range repo.Textgroups {
+		if repo.Textgroups[i].TextgroupID == groupID {
+			index = i
+		}
+	}
+	if index == -1 {
+		repo.Textgroups = append(repo.Textgroups, Textgroup{TextgroupID: groupID})
+		index = len(repo.Textgroups) - 1
+	}
+	if _, found := repo.GetCatalogEntry(work.WorkID); !found {
+		if entry.URN == "" {
+			entry.URN = work.WorkID
+		}
+		if entry.CitationScheme == "" {
+			entry.CitationScheme = strings.Join(work.CitationScheme, ",")
+		}
+		repo.Catalog = append(repo.Catalog[:len(repo.Catalog):len(repo.Catalog)], entry)
+	}
+	if len(work.CitationScheme) == 0 {
+		catalogEntry, _ := repo.GetCatalogEntry(work.WorkID)
+		work.CitationScheme = citationScheme(catalogEntry)
+	}
+	group := &repo.Textgroups[index]
+	group.Works = append(group.Works[:len(group.Works):len(group.Works)], work)
+	return nil
+}
+
+// GetCatalogEntry returns the CatalogEntry of the Work a CTS URN refers to
+// along with a bool whether it has found one.
+// The URN may carry a passage and may point to a notional work, in which case
+// the first matching entry is returned.
+func (repo TextRepository) GetCatalogEntry(URNString string) (CatalogEntry, bool) {
+	urn, err := ParseCTSURN(URNString)
+	if err != nil {
+		return CatalogEntry{}, false
+	}
+	urn = urn.DropPassage()
+	for _, entry := range repo.Catalog {
+		entryURN := SplitCTS(entry.URN).DropPassage()
+		if entryURN.ID == urn.ID {
+			return entry, true
+		}
+	}
+	for _, entry := range repo.Catalog {
+		if SplitCTS(entry.URN).DropPassage().IsContainedBy(urn) {
+			return entry, true
+		}
+	}
+	return CatalogEntry{}, false
+}
+
+// FindWorks returns all Works a CTS URN refers to, in repository order.
+// A URN at textgroup level returns all Works of the Textgroup,
+// a URN at notional work level all versions and exemplars of the work.
+func (repo TextRepository) FindWorks(URNString string) ([]Work, error) {
+	urn, err := ParseCTSURN(URNString)
+	if err != nil {
+		return []Work{}, err
+	}
+	urn = urn.DropPassage()
+	works := []Work{}
+	for _, group := range repo.Textgroups {
+		for _, work := range group.Works {
+			if SplitCTS(work.WorkID).DropPassage().IsContainedBy(urn) {
+				works = append(works, work)
+			}
+		}
+	}
+	if len(works) == 0 {
+		return works, errors.New("FindWorks: no Work found for " + URNString)
+	}
+	return works, nil
+}
+
+// FindWork returns the Work a CTS URN refers to. If the URN matches
+// more than one Work, e.g. at notional work level, the Work whose WorkID
+// equals the URN is preferred, followed by the first matching Work in repository order.
+func (repo TextRepository) FindWork(URNString string) (Work, error) {
+	if work, found := repo.getWork(SplitCTS(URNString).DropPassage().ID); found {
+		return work, nil
+	}
+	works, err := repo.FindWorks(URNString)
+	if err != nil {
+		return Work{}, err
+	}
+	return works[0], nil
+}
+
+// ResolveURN returns the Work a CTS URN refers to along with the URN rewritten
+// to the work hierarchy of that Work, so that its passage component
+// can be looked up in the Work, e.g. with GetPassageByID or ExtractTextByID
+func (repo TextRepository) ResolveURN(URNString string) (Work, string, error) {
+	urn, err := ParseCTSURN(URNString)
+	if err != nil {
+		return Work{}, "", err
+	}
+	work, err := repo.FindWork(URNString)
+	if err != nil {
+		return Work{}, "", err
+	}
+	return work, SplitCTS(work.WorkID).withPassage(urn.Passage), nil
+}
+
+// GetPassages returns the Passages a CTS URN refers to in document order.
+// A URN without passage component returns all Passages of the Work,
+// a citation node that is not a Passage itself returns the Passages it contains,
+// e.g. all lines of a book in a Work cited by book and line.
+// Subreferences are ignored, as a Passage is always returned as a whole.
+func (repo TextRepository) GetPassages(URNString string) ([]Passage, error) {
+	work, resolved, err := repo.ResolveURN(URNString)
+	if err != nil {
+		return []Passage{}, err
+	}
+	return passagesByID(resolved, work)
+}
+
+// GetValidReff returns the CTS URNs of the citation nodes at the given depth within a CTS URN,
+// see GetValidReff. The URNs use the work hierarchy of the Work the URN was resolved to.
+func (repo TextRepository) GetValidReff(URNString string, depth int) ([]string, error) {
+	work, resolved, err := repo.ResolveURN(URNString)
+	if err != nil {
+		return []string{}, err
+	}
+	return GetValidReff(resolved, depth, work)
+}
+
+// GetContainer returns the CTS URN of the citation node at the given depth that contains
+// the citation node of a CTS URN, see GetContainer
+func (repo TextRepository) GetContainer(URNString string, depth int) (string, error) {
+	work, resolved, err := repo.ResolveURN(URNString)
+	if err != nil {
+		return "", err
+	}
+	return GetContainer(resolved, depth, work)
+}
+
+// ExtractTextByID extracts the textual information a CTS URN refers to,
+// see ExtractTextByID. The IDs of the result use the work hierarchy of the Work
+// the URN was resolved to.
+func (repo TextRepository) ExtractTextByID(URNString string) ([]TextAndID, error) {
+	work, resolved, err := repo.ResolveURN(URNString)
+	if err != nil {
+		return []TextAndID{}, err
+	}
+	return ExtractTextByID(resolved, work)
+}
+
+// ExtractSpansByID extracts the textual information a CTS URN refers to along with its
+// offsets in the Passages, see ExtractSpansByID and TextRepository.ExtractTextByID
+func (repo TextRepository) ExtractSpansByID(URNString string) ([]TextSpan, error) {
+	work, resolved, err := repo.ResolveURN(URNString)
+	if err != nil {
+		return []TextSpan{}, err
+	}
+	return ExtractSpansByID(resolved, work)
+}
+
+// getWork returns the Work with the given WorkID along with a bool whether it has found it
+func (repo TextRepository) getWork(workID string) (Work, bool) {
+	for _, group := range repo.Textgroups {
+		for _, work := range group.Works {
+			if work.WorkID == workID {
+				return work, true
+			}
+		}
+	}
+	return Work{}, false
+}
+
+// documentOrder returns the Passages of a Work following the Next links from Work.First
+func documentOrder(work Work) ([]Passage, error) {
+	passages := []Passage{}
+	it := NewIterator(work)
+	for it.Next() {
+		passages = append(passages, it.Passage())
+	}
+	if err := it.Err(); err != nil {
+		return []Passage{}, err
+	}
+	return passages, nil
+}
+
+// citationScheme returns the citation levels named in the citationScheme of a CatalogEntry
+func citationScheme(entry CatalogEntry) []string {
+	if entry.CitationScheme == "" {
+		return nil
+	}
+	levels := strings.Split(entry.CitationScheme, ",")
+	for i := range levels {
+		levels[i] = strings.TrimSpace(levels[i])
+	}
+	return levels
+}
+
+// textgroupID returns the CTS URN string of the textgroup of urn
+func textgroupID(urn CTSURN) string {
+	return strings.Join([]string{urn.Base, urn.Protocol, urn.Namespace, urn.Textgroup, ""}, ":")
+}
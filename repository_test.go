@@ -0,0 +1,93 @@
+package gocite_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+func testRepository(t *testing.T) gocite.TextRepository {
+	data, err := gocite.ReadCEX(strings.NewReader(testCEX))
+	if err != nil {
+		t.Fatal("Error calling ReadCEX: ", err)
+	}
+	return gocite.NewTextRepository(data)
+}
+
+func TestTextRepositoryFindWork(t *testing.T) {
+	repo := testRepository(t)
+	tests := []struct {
+		input, workID string
+		works         int
+	}{
+		{input: "urn:cts:greekLit:tlg0012.tlg001.msA:1.1", workID: "urn:cts:greekLit:tlg0012.tlg001.msA:", works: 1},
+		{input: "urn:cts:greekLit:tlg0012.tlg001:1.1", workID: "urn:cts:greekLit:tlg0012.tlg001.msA:", works: 1},
+		{input: "urn:cts:greekLit:tlg0012.tlg002:", workID: "urn:cts:greekLit:tlg0012.tlg002.msA:", works: 1},
+		{input: "urn:cts:greekLit:tlg0012:", workID: "urn:cts:greekLit:tlg0012.tlg001.msA:", works: 2},
+	}
+	for _, test := range tests {
+		work, err := repo.FindWork(test.input)
+		if err != nil || work.WorkID != test.workID {
+			t.Error("For", test.input, "expected", test.workID, "got", work.WorkID, err)
+		}
+		works, err := repo.FindWorks(test.input)
+		if err != nil || len(works) != test.works {
+			t.Error("For", test.input, "expected", test.works, "works, got", len(works), err)
+		}
+	}
+	if _, err := repo.FindWork("urn:cts:greekLit:tlg0013.tlg001:1.1"); err == nil {
+		t.Error("expected an error for an unknown work")
+	}
+	if _, err := repo.FindWork("urn:cts:greekLit:tlg0012.tlg001:1-2-3"); err == nil {
+		t.Error("expected an error for an invalid urn")
+	}
+}
+
+func TestTextRepositoryExtractTextByID(t *testing.T) {
+	repo := testRepository(t)
+	text, err := repo.ExtractTextByID("urn:cts:greekLit:tlg0012.tlg001:1.2-1.3")
+	if err != nil {
+		t.Fatal("Error calling ExtractTextByID: ", err)
+	}
+	if len(text) != 2 || text[0].ID != "urn:cts:greekLit:tlg0012.tlg001.msA:1.2" || text[1].Text != "πολλὰς δ' ἰφθίμους ψυχὰς Ἄϊδι προΐαψεν" {
+		t.Error("unexpected text", text)
+	}
+	passages, err := repo.GetPassages("urn:cts:greekLit:tlg0012.tlg001:")
+	if err != nil || len(passages) != 3 {
+		t.Error("expected 3 passages, got", passages, err)
+	}
+	passages, err = repo.GetPassages("urn:cts:greekLit:tlg0012.tlg001:1.1@μῆνιν")
+	if err != nil || len(passages) != 1 || passages[0].PassageID != "urn:cts:greekLit:tlg0012.tlg001.msA:1.1" {
+		t.Error("expected passage 1.1, got", passages, err)
+	}
+	passages, err = repo.GetPassages("urn:cts:greekLit:tlg0012.tlg001:1")
+	if err != nil || len(passages) != 3 {
+		t.Error("expected the 3 passages of book 1, got", passages, err)
+	}
+	if _, err = repo.GetPassages("urn:cts:greekLit:tlg0012.tlg001:2"); err == nil {
+		t.Error("expected an error for a book without passages")
+	}
+}
+
+func TestTextRepositoryCatalog(t *testing.T) {
+	repo := testRepository(t)
+	entry, found := repo.GetCatalogEntry("urn:cts:greekLit:tlg0012.tlg001:1.1")
+	if !found || entry.WorkTitle != "Iliad" || entry.CitationScheme != "book,line" {
+		t.Error("unexpected catalog entry", entry, found)
+	}
+	work := gocite.Work{WorkID: "urn:cts:greekLit:tlg0011.tlg001.perseus-grc1:"}
+	if err := repo.AddWork(work, gocite.CatalogEntry{WorkTitle: "Ajax", Online: true}); err != nil {
+		t.Fatal("Error calling AddWork: ", err)
+	}
+	if err := repo.AddWork(work, gocite.CatalogEntry{}); err == nil {
+		t.Error("expected an error when adding a work twice")
+	}
+	if len(repo.Textgroups) != 2 || repo.Textgroups[1].TextgroupID != "urn:cts:greekLit:tlg0011:" {
+		t.Error("unexpected textgroups", repo.Textgroups)
+	}
+	entry, found = repo.GetCatalogEntry("urn:cts:greekLit:tlg0011.tlg001:")
+	if !found || entry.WorkTitle != "Ajax" || entry.URN != work.WorkID {
+		t.Error("unexpected catalog entry", entry, found)
+	}
+}
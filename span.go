@@ -0,0 +1,156 @@
+package gocite
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// TextSpan is a part of the text of the Passage PassageID: the runes from Start up to,
+// but not including, End. ID is the CTS URN of the span as returned by ExtractTextByID.
+// StartSubref and EndSubref hold the subreferences delimiting the span, resolved to an explicit
+// occurrence, e.g. μῆνιν[1] for @μῆνιν. A span of a single word carries it in both.
+type TextSpan struct {
+	ID, PassageID          string
+	Text                   string
+	Start, End             int
+	StartSubref, EndSubref Subreference
+}
+
+// ExtractSpansByID extracts the textual information from a Passage or multiple Passages in a Work
+// like ExtractTextByID, with the rune offsets of every extract in the text of its Passage
+func ExtractSpansByID(ctsID string, work Work) ([]TextSpan, error) {
+	if !IsCTSURN(ctsID) {
+		return []TextSpan{}, errors.New("urn is not a valid cts urn")
+	}
+	urn := SplitCTS(ctsID)
+	if urn.InValid {
+		return []TextSpan{}, errors.New("invalid urn")
+	}
+	if !urn.Range && !urn.Start.Subref.Exists {
+		p, err := GetPassageByID(ctsID, work)
+		if err != nil {
+			leaves, leafErr := GetLeafPassages(ctsID, work)
+			if leafErr != nil {
+				return []TextSpan{}, err
+			}
+			return passageSpans(leaves)
+		}
+		span, err := passageSpan(p)
+		if err != nil {
+			return []TextSpan{}, err
+		}
+		span.ID = ctsID
+		return []TextSpan{span}, nil
+	}
+	if !urn.Range || urn.Start.CitationString() == urn.End.CitationString() && (urn.Start.Subref.Exists || urn.End.Subref.Exists) {
+		startSubref, endSubref := urn.Start.Subref, urn.Start.Subref
+		if urn.Range {
+			if !urn.Start.Subref.Exists || !urn.End.Subref.Exists {
+				return []TextSpan{}, errors.New("substringing in the same line has the format 1@start-1@end")
+			}
+			endSubref = urn.End.Subref
+		}
+		p, err := GetPassageByID(urn.withPassage(urn.Start.CitationString()), work)
+		if err != nil {
+			return []TextSpan{}, err
+		}
+		span, err := passageSpan(p)
+		if err != nil {
+			return []TextSpan{}, err
+		}
+		start, _, err := FindSubref(startSubref, span.Text)
+		if err != nil {
+			return []TextSpan{}, err
+		}
+		_, end, err := FindSubref(endSubref, span.Text)
+		if err != nil {
+			return []TextSpan{}, err
+		}
+		if end <= start {
+			return []TextSpan{}, errors.New("subreference " + endSubref.String() + " precedes " + startSubref.String())
+		}
+		span = span.cut(start, end)
+		span.ID = ctsID
+		span.StartSubref, span.EndSubref = resolvedSubref(startSubref), resolvedSubref(endSubref)
+		return []TextSpan{span}, nil
+	}
+	startID, err := rangeBound(urn, urn.Start, true, work)
+	if err != nil {
+		return []TextSpan{}, err
+	}
+	endID, err := rangeBound(urn, urn.End, false, work)
+	if err != nil {
+		return []TextSpan{}, err
+	}
+	passages, err := passagesBetween(startID, endID, work)
+	if err != nil {
+		return []TextSpan{}, err
+	}
+	spans, err := passageSpans(passages)
+	if err != nil {
+		return []TextSpan{}, err
+	}
+	first, last := 0, len(spans)-1
+	start, end := spans[first].Start, spans[last].End
+	if urn.Start.Subref.Exists {
+		if start, _, err = FindSubref(urn.Start.Subref, spans[first].Text); err != nil {
+			return []TextSpan{}, err
+		}
+	}
+	if urn.End.Subref.Exists {
+		if _, end, err = FindSubref(urn.End.Subref, spans[last].Text); err != nil {
+			return []TextSpan{}, err
+		}
+	}
+	if first == last && end <= start {
+		return []TextSpan{}, errors.New("range ends before it starts")
+	}
+	if urn.Start.Subref.Exists {
+		spans[first] = spans[first].cut(start, spans[first].End)
+		spans[first].ID = urn.withPassage(urn.Start.ID)
+		spans[first].StartSubref = resolvedSubref(urn.Start.Subref)
+	}
+	if urn.End.Subref.Exists {
+		spans[last] = spans[last].cut(spans[last].Start, end)
+		spans[last].ID = urn.withPassage(urn.End.ID)
+		spans[last].EndSubref = resolvedSubref(urn.End.Subref)
+	}
+	return spans, nil
+}
+
+// passageSpan returns a TextSpan covering the whole text of a Passage
+func passageSpan(p Passage) (TextSpan, error) {
+	txt, found := PassageText(p)
+	if !found {
+		return TextSpan{}, errors.New("txt not found")
+	}
+	return TextSpan{ID: p.PassageID, PassageID: p.PassageID, Text: txt, End: utf8.RuneCountInString(txt)}, nil
+}
+
+// passageSpans returns a TextSpan covering the whole text of each of the Passages
+func passageSpans(passages []Passage) ([]TextSpan, error) {
+	spans := []TextSpan{}
+	for _, p := range passages {
+		span, err := passageSpan(p)
+		if err != nil {
+			return []TextSpan{}, err
+		}
+		spans = append(spans, span)
+	}
+	return spans, nil
+}
+
+// cut returns the part of span from the rune offset start to end,
+// both counted from the beginning of the text of its Passage
+func (span TextSpan) cut(start, end int) TextSpan {
+	runes := []rune(span.Text)
+	span.Text = string(runes[start-span.Start : end-span.Start])
+	span.Start, span.End = start, end
+	return span
+}
+
+// resolvedSubref returns subref with the occurrence it points to made explicit
+func resolvedSubref(subref Subreference) Subreference {
+	subref.Index = subref.Occurrence()
+	return subref
+}
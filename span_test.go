@@ -0,0 +1,44 @@
+package gocite_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+func TestExtractSpansByID(t *testing.T) {
+	work := testCitationWork(t)
+	menin := gocite.Subreference{Exists: true, Token: "Μῆνιν", Index: 1}
+	tests := []struct {
+		input    string
+		expected []gocite.TextSpan
+	}{
+		{input: "1.1@Μῆνιν", expected: []gocite.TextSpan{
+			{ID: citationWork + "1.1@Μῆνιν", PassageID: citationWork + "1.1", Text: "Μῆνιν", Start: 0, End: 5, StartSubref: menin, EndSubref: menin},
+		}},
+		{input: "1.1@ἄειδε-1.2@μυρί'", expected: []gocite.TextSpan{
+			{ID: citationWork + "1.1@ἄειδε", PassageID: citationWork + "1.1", Text: "ἄειδε θεὰ Πηληϊάδεω Ἀχιλῆος", Start: 6, End: 33,
+				StartSubref: gocite.Subreference{Exists: true, Token: "ἄειδε", Index: 1}},
+			{ID: citationWork + "1.2@μυρί'", PassageID: citationWork + "1.2", Text: "οὐλομένην, ἣ μυρί'", Start: 0, End: 18,
+				EndSubref: gocite.Subreference{Exists: true, Token: "μυρί'", Index: 1}},
+		}},
+		{input: "2.2@Δία-2.2@ὕπνος", expected: []gocite.TextSpan{
+			{ID: citationWork + "2.2@Δία-2.2@ὕπνος", PassageID: citationWork + "2.2", Text: "Δία δ' οὐκ ἔχε νήδυμος ὕπνος", Start: 17, End: 45,
+				StartSubref: gocite.Subreference{Exists: true, Token: "Δία", Index: 1}, EndSubref: gocite.Subreference{Exists: true, Token: "ὕπνος", Index: 1}},
+		}},
+		{input: "2.1", expected: []gocite.TextSpan{
+			{ID: citationWork + "2.1", PassageID: citationWork + "2.1", Text: "Ἄλλοι μέν ῥα θεοί τε καὶ ἀνέρες ἱπποκορυσταὶ", Start: 0, End: 44},
+		}},
+	}
+	for _, test := range tests {
+		spans, err := gocite.ExtractSpansByID(citationWork+test.input, work)
+		if err != nil || !reflect.DeepEqual(spans, test.expected) {
+			t.Error("For", test.input, "expected", test.expected, "got", spans, err)
+		}
+	}
+	spans, err := gocite.ExtractSpansByID(citationWork+"1", work)
+	if err != nil || len(spans) != 3 || spans[2].PassageID != citationWork+"1.3" || spans[2].Start != 0 {
+		t.Error("expected the spans of book 1, got", spans, err)
+	}
+}
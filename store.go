@@ -0,0 +1,101 @@
+package gocite
+
+import (
+	"errors"
+	"sync"
+)
+
+// Store holds a TextRepository for concurrent use, e.g. by HTTP handlers reading texts
+// while an ingest goroutine adds and replaces Works. Changes never touch the slices of
+// the repository they replace, so that every Snapshot stays valid and readers only hold
+// the lock while taking one. Works handed to a Store must not be changed by hand afterwards,
+// use the functions of this package, which return new Works, or Clone.
+type Store struct {
+	mu   sync.RWMutex
+	repo TextRepository
+}
+
+// NewStore returns a Store holding repo
+func NewStore(repo TextRepository) *Store {
+	return &Store{repo: repo}
+}
+
+// Snapshot returns the TextRepository as it is now. Later changes to the Store do not affect it.
+func (s *Store) Snapshot() TextRepository {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.repo
+}
+
+// FindWork returns the Work a CTS URN refers to, see TextRepository.FindWork
+func (s *Store) FindWork(URNString string) (Work, error) {
+	return s.Snapshot().FindWork(URNString)
+}
+
+// GetPassages returns the Passages a CTS URN refers to, see TextRepository.GetPassages
+func (s *Store) GetPassages(URNString string) ([]Passage, error) {
+	return s.Snapshot().GetPassages(URNString)
+}
+
+// ExtractTextByID extracts the textual information a CTS URN refers to, see TextRepository.ExtractTextByID
+func (s *Store) ExtractTextByID(URNString string) ([]TextAndID, error) {
+	return s.Snapshot().ExtractTextByID(URNString)
+}
+
+// ExtractSpansByID extracts the textual information a CTS URN refers to along with its offsets,
+// see TextRepository.ExtractSpansByID
+func (s *Store) ExtractSpansByID(URNString string) ([]TextSpan, error) {
+	return s.Snapshot().ExtractSpansByID(URNString)
+}
+
+// AddWork adds a Work to the Store, see TextRepository.AddWork
+func (s *Store) AddWork(work Work, entry CatalogEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo := s.repo
+	repo.Textgroups = append([]Textgroup{}, s.repo.Textgroups...)
+	if err := repo.AddWork(work, entry); err != nil {
+		return err
+	}
+	s.repo = repo
+	return nil
+}
+
+// ReplaceWork replaces the Work with the WorkID of work in one step,
+// so that readers see either the old or the new Work
+func (s *Store) ReplaceWork(work Work) error {
+	return s.UpdateWork(work.WorkID, func(Work) (Work, error) {
+		return work, nil
+	})
+}
+
+// UpdateWork replaces the Work workID with the Work update returns for it.
+// Other changes to the Store wait until update has returned, so that it can
+// derive the new Work from the current one, e.g. with InsertPassage or DelPassage.
+// If update returns an error, the Store is left unchanged.
+func (s *Store) UpdateWork(workID string, update func(Work) (Work, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, group := range s.repo.Textgroups {
+		for j, work := range group.Works {
+			if work.WorkID != workID {
+				continue
+			}
+			updated, err := update(work)
+			if err != nil {
+				return err
+			}
+			if updated.WorkID != workID {
+				return errors.New("UpdateWork: WorkID changed from " + workID + " to " + updated.WorkID)
+			}
+			group.Works = append([]Work{}, group.Works...)
+			group.Works[j] = updated
+			repo := s.repo
+			repo.Textgroups = append([]Textgroup{}, s.repo.Textgroups...)
+			repo.Textgroups[i] = group
+			s.repo = repo
+			return nil
+		}
+	}
+	return errors.New("UpdateWork: Work " + workID + " not found")
+}
@@ -0,0 +1,98 @@
+package gocite_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+func testStore(t *testing.T) *gocite.Store {
+	data, err := gocite.ReadCEX(strings.NewReader(citationCEX))
+	if err != nil {
+		t.Fatal("Error calling ReadCEX: ", err)
+	}
+	return gocite.NewStore(gocite.NewTextRepository(data))
+}
+
+func TestStoreUpdates(t *testing.T) {
+	store := testStore(t)
+	snapshot := store.Snapshot()
+	err := store.UpdateWork(citationWork, func(work gocite.Work) (gocite.Work, error) {
+		return gocite.DelRange(citationWork+"2", work)
+	})
+	if err != nil {
+		t.Fatal("Error calling UpdateWork: ", err)
+	}
+	if _, err := store.ExtractTextByID(citationWork + "2.1"); err == nil {
+		t.Error("expected book 2 to be deleted")
+	}
+	if text, err := snapshot.ExtractTextByID(citationWork + "2.1"); err != nil || len(text) != 1 {
+		t.Error("expected the snapshot to keep book 2, got", text, err)
+	}
+	if err := store.UpdateWork(citationWork, func(work gocite.Work) (gocite.Work, error) {
+		return work, errors.New("refused")
+	}); err == nil {
+		t.Error("expected the error of the update")
+	}
+	if err := store.ReplaceWork(gocite.Work{WorkID: "urn:cts:greekLit:tlg0012.tlg002.msA:"}); err == nil {
+		t.Error("expected an error replacing an unknown work")
+	}
+	if err := store.AddWork(gocite.Work{WorkID: "urn:cts:greekLit:tlg0012.tlg002.msA:"}, gocite.CatalogEntry{WorkTitle: "Odyssey"}); err != nil {
+		t.Error("Error calling AddWork: ", err)
+	}
+	if len(snapshot.Textgroups[0].Works) != 1 || len(store.Snapshot().Textgroups[0].Works) != 2 {
+		t.Error("expected the new work in the store only")
+	}
+}
+
+func TestStoreConcurrentAccess(t *testing.T) {
+	store := testStore(t)
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				text, err := store.ExtractTextByID(citationWork + "1.1-2.2")
+				if err != nil || len(text) < 5 || text[0].ID != citationWork+"1.1" {
+					t.Error("unexpected text during updates", text, err)
+					return
+				}
+				if _, err := store.GetPassages(citationWork + "1"); err != nil {
+					t.Error("Error calling GetPassages: ", err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		id := citationWork + "1.3." + strconv.Itoa(i)
+		err := store.UpdateWork(citationWork, func(work gocite.Work) (gocite.Work, error) {
+			return gocite.InsertPassagesAfter(citationWork+"1.3", []gocite.Passage{{PassageID: id, Analysis: txtAnalysis("new")}}, work)
+		})
+		if err != nil {
+			t.Error("Error calling UpdateWork: ", err)
+		}
+		if i%50 == 0 {
+			work, _ := store.FindWork(citationWork)
+			if err := store.ReplaceWork(gocite.Clone(work)); err != nil {
+				t.Error("Error calling ReplaceWork: ", err)
+			}
+		}
+	}
+	close(stop)
+	wg.Wait()
+	if passages, err := store.GetPassages(citationWork); err != nil || len(passages) != 205 {
+		t.Error("expected 205 passages, got", len(passages), err)
+	}
+}
@@ -0,0 +1,53 @@
+package gocite
+
+import (
+	"errors"
+	"strconv"
+	"unicode"
+)
+
+// FindSubref returns the rune offsets of the start and the end of the text a Subreference
+// points to in the text of a passage, the end being exclusive. Following the CTS URN specification,
+// the Token only matches whole words, so that "is" matches neither "This" nor "island",
+// and Index selects which of these matches is meant, the first if no Index is given.
+func FindSubref(subref Subreference, text string) (start, end int, err error) {
+	if !subref.Exists || subref.Token == "" {
+		return 0, 0, errors.New("FindSubref: empty subreference")
+	}
+	runes := []rune(text)
+	token := []rune(subref.Token)
+	count := 0
+	for i := 0; i+len(token) <= len(runes); i++ {
+		if !hasRunesAt(runes, token, i) {
+			continue
+		}
+		if isWordRune(token[0]) && i > 0 && isWordRune(runes[i-1]) {
+			continue
+		}
+		j := i + len(token)
+		if isWordRune(token[len(token)-1]) && j < len(runes) && isWordRune(runes[j]) {
+			continue
+		}
+		count++
+		if count == subref.Occurrence() {
+			return i, j, nil
+		}
+	}
+	return 0, 0, errors.New("FindSubref: occurrence " + strconv.Itoa(subref.Occurrence()) + " of " + subref.Token +
+		" not found, found " + strconv.Itoa(count))
+}
+
+// hasRunesAt tests whether token occurs in runes at index i
+func hasRunesAt(runes, token []rune, i int) bool {
+	for k := range token {
+		if runes[i+k] != token[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// isWordRune reports whether r is part of a word: a letter, a digit or a combining mark
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
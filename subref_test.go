@@ -0,0 +1,55 @@
+package gocite_test
+
+import (
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+func TestFindSubref(t *testing.T) {
+	text := "Μῆνιν ἄειδε θεὰ, This is is the island's 10th is."
+	tests := []struct {
+		subref     string
+		start, end int
+		err        bool
+	}{
+		{subref: "Μῆνιν", start: 0, end: 5},
+		{subref: "θεὰ,", start: 12, end: 16},
+		{subref: "is", start: 22, end: 24},
+		{subref: "is[2]", start: 25, end: 27},
+		{subref: "is[3]", start: 46, end: 48},
+		{subref: "is[4]", err: true},
+		{subref: "isl", err: true},
+		{subref: "10th", start: 41, end: 45},
+		{subref: "Μῆνι", err: true},
+	}
+	for _, test := range tests {
+		urn := gocite.SplitCTS("urn:cts:greekLit:tlg0012.tlg001.msA:1.1@" + test.subref)
+		start, end, err := gocite.FindSubref(urn.Start.Subref, text)
+		if (err != nil) != test.err || start != test.start || end != test.end {
+			t.Error("For", test.subref, "expected", test.start, test.end, test.err, "got", start, end, err)
+		}
+	}
+}
+
+func TestExtractTextByIDSubref(t *testing.T) {
+	work := testCitationWork(t)
+	tests := []struct {
+		input, expected string
+	}{
+		{input: "1.2@ἣ", expected: "ἣ"},
+		{input: "1.2@Ἀχαιοῖς-1.2@ἔθηκε,", expected: "Ἀχαιοῖς ἄλγε' ἔθηκε,"},
+		{input: "2.1@τε-2.1@ἀνέρες", expected: "τε καὶ ἀνέρες"},
+	}
+	for _, test := range tests {
+		text, err := gocite.ExtractTextByID(citationWork+test.input, work)
+		if err != nil || len(text) != 1 || text[0].Text != test.expected {
+			t.Error("For", test.input, "expected", test.expected, "got", text, err)
+		}
+	}
+	for _, input := range []string{"1.2@ἣ[2]", "2.1@ἀνέρες-2.1@τε", "1.1@Μῆνι"} {
+		if _, err := gocite.ExtractTextByID(citationWork+input, work); err == nil {
+			t.Error("For", input, "expected an error")
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package gocite
+
+import (
+	"errors"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of problems reported by ValidateWork
+const (
+	ProblemDuplicateID  = "duplicate id"
+	ProblemIndex        = "index"
+	ProblemDanglingLink = "dangling link"
+	ProblemMismatchedID = "mismatched id"
+	ProblemBrokenLink   = "broken link"
+	ProblemCycle        = "cycle"
+	ProblemUnreachable  = "unreachable"
+	ProblemFirstLast    = "first/last"
+)
+
+// WorkProblem is a single inconsistency ValidateWork found in a Work.
+// Index is the slice index of the Passage concerned, -1 for problems of the Work itself.
+type WorkProblem struct {
+	Kind      string
+	Index     int
+	PassageID string
+	Message   string
+}
+
+// WorkReport lists the problems ValidateWork found in a Work
+type WorkReport struct {
+	WorkID   string
+	Problems []WorkProblem
+}
+
+// Valid tests whether no problems were found
+func (report WorkReport) Valid() bool {
+	return len(report.Problems) == 0
+}
+
+// Error returns the problems of the report as a single string
+func (report WorkReport) Error() string {
+	messages := []string{}
+	for _, problem := range report.Problems {
+		messages = append(messages, problem.Kind+": "+problem.Message)
+	}
+	return "Work " + report.WorkID + ": " + strings.Join(messages, "; ")
+}
+
+// ValidateWork checks the links between the Passages of a Work: that PassageIDs are unique,
+// that Passage.Index matches the slice index, that every Prev and Next points to an existing
+// Passage with the PassageID it names and links back, that First and Last are the ends of
+// a single chain without cycles, and that every Passage can be reached from First.
+// Empty Passages, which older versions of DelPassage left behind, are skipped.
+func ValidateWork(work Work) WorkReport {
+	report := WorkReport{WorkID: work.WorkID, Problems: []WorkProblem{}}
+	add := func(kind string, index int, message string) {
+		problem := WorkProblem{Kind: kind, Index: index, Message: message}
+		if index >= 0 {
+			problem.PassageID = work.Passages[index].PassageID
+		}
+		report.Problems = append(report.Problems, problem)
+	}
+	live := 0
+	seen := map[string]int{}
+	for i, p := range work.Passages {
+		if p.PassageID == "" {
+			continue
+		}
+		live++
+		if first, found := seen[p.PassageID]; found {
+			add(ProblemDuplicateID, i, p.PassageID+" is also found at index "+strconv.Itoa(first))
+		} else {
+			seen[p.PassageID] = i
+		}
+		if p.Index != i {
+			add(ProblemIndex, i, "Index "+strconv.Itoa(p.Index)+" at slice index "+strconv.Itoa(i))
+		}
+		checkLink := func(name string, loc PassLoc, back func(Passage) PassLoc) {
+			if !loc.Exists {
+				return
+			}
+			if !validSlot(loc.Index, work) {
+				add(ProblemDanglingLink, i, name+" points to index "+strconv.Itoa(loc.Index)+" without Passage")
+				return
+			}
+			target := work.Passages[loc.Index]
+			if target.PassageID != loc.PassageID {
+				add(ProblemMismatchedID, i, name+" names "+loc.PassageID+" but points to "+target.PassageID)
+			}
+			if link := back(target); !link.Exists || link.Index != i {
+				add(ProblemBrokenLink, i, name+" "+target.PassageID+" does not link back")
+			}
+		}
+		checkLink("Next", p.Next, func(target Passage) PassLoc { return target.Prev })
+		checkLink("Prev", p.Prev, func(target Passage) PassLoc { return target.Next })
+	}
+	if live == 0 {
+		return report
+	}
+	checkEnd := func(name string, loc PassLoc, open func(Passage) bool) {
+		switch {
+		case !loc.Exists:
+			add(ProblemFirstLast, -1, name+" is not set")
+		case !validSlot(loc.Index, work):
+			add(ProblemFirstLast, -1, name+" points to index "+strconv.Itoa(loc.Index)+" without Passage")
+		case work.Passages[loc.Index].PassageID != loc.PassageID:
+			add(ProblemFirstLast, -1, name+" names "+loc.PassageID+" but points to "+work.Passages[loc.Index].PassageID)
+		case !open(work.Passages[loc.Index]):
+			add(ProblemFirstLast, -1, name+" "+loc.PassageID+" is not an end of the Work")
+		}
+	}
+	checkEnd("First", work.First, func(p Passage) bool { return !p.Prev.Exists })
+	checkEnd("Last", work.Last, func(p Passage) bool { return !p.Next.Exists })
+	if !work.First.Exists || !validSlot(work.First.Index, work) {
+		return report
+	}
+	visited := make([]bool, len(work.Passages))
+	cursor := work.First.Index
+	for {
+		visited[cursor] = true
+		next := work.Passages[cursor].Next
+		if !next.Exists {
+			if cursor != work.Last.Index {
+				add(ProblemFirstLast, cursor, "the chain from First ends before Last")
+			}
+			break
+		}
+		if !validSlot(next.Index, work) {
+			break
+		}
+		if visited[next.Index] {
+			add(ProblemCycle, cursor, "Next leads back to "+work.Passages[next.Index].PassageID)
+			break
+		}
+		cursor = next.Index
+	}
+	for i, p := range work.Passages {
+		if p.PassageID != "" && !visited[i] {
+			add(ProblemUnreachable, i, p.PassageID+" cannot be reached from First")
+		}
+	}
+	return report
+}
+
+// Orders in which RepairWork links the Passages of a Work
+const (
+	RepairByLinks    = 0
+	RepairBySlice    = 1
+	RepairByCitation = 2
+)
+
+// RepairWork returns a Work whose Passages are linked anew in the given order and stored in it,
+// with First, Last, Index values and the ID index rebuilt and deleted Passages removed.
+// RepairByLinks keeps the document order the intact links give, as ValidateWork checks them:
+// it follows the chain from First, then the chains of the Passages that cannot be reached from it
+// in the order of the Passages slice, so a valid Work keeps its document order.
+// RepairBySlice links the Passages in the order of the Passages slice, discarding all links.
+// RepairByCitation sorts them by their citation, e.g. 1.2 before 1.10 before 2.1,
+// which requires every PassageID to cite a single node of the Work.
+// RepairWork refuses Works with duplicated PassageIDs, as it cannot know which one to keep.
+// The repair can be logged, see WithLogger.
+func RepairWork(work Work, order int, opts ...Option) (Work, error) {
+	o := newOptions(opts)
+	live := []int{}
+	seen := map[string]bool{}
+	for i, p := range work.Passages {
+		if p.PassageID == "" {
+			continue
+		}
+		if seen[p.PassageID] {
+			return work, errors.New("RepairWork: duplicated PassageID " + p.PassageID)
+		}
+		seen[p.PassageID] = true
+		live = append(live, i)
+	}
+	switch order {
+	case RepairByLinks:
+		live = linkOrder(live, work)
+	case RepairBySlice:
+	case RepairByCitation:
+		citations := make(map[int][]string, len(live))
+		workID := SplitCTS(work.WorkID).DropPassage().ID
+		for _, i := range live {
+			urn := SplitCTS(work.Passages[i].PassageID)
+			if urn.InValid || urn.Range || urn.Start.Subref.Exists || urn.Passage == "" || urn.DropPassage().ID != workID {
+				return work, errors.New("RepairWork: cannot sort " + work.Passages[i].PassageID + " by citation")
+			}
+			citations[i] = urn.Start.Citation
+		}
+		sort.SliceStable(live, func(i, j int) bool {
+			return citationLess(citations[live[i]], citations[live[j]])
+		})
+	default:
+		return work, errors.New("RepairWork: unknown order " + strconv.Itoa(order))
+	}
+	passages := make([]Passage, len(live))
+	for i, index := range live {
+		passages[i] = work.Passages[index]
+	}
+	result := Work{WorkID: work.WorkID, Ordered: true, CitationScheme: work.CitationScheme}
+	for i := range passages {
+		passages[i].Index = i
+		passages[i].Prev, passages[i].Next = PassLoc{}, PassLoc{}
+		if i > 0 {
+			passages[i].Prev = PassLoc{Exists: true, PassageID: passages[i-1].PassageID, Index: i - 1}
+			passages[i-1].Next = PassLoc{Exists: true, PassageID: passages[i].PassageID, Index: i}
+		}
+	}
+	result.Passages = passages
+	if len(passages) > 0 {
+		result.First = PassLoc{Exists: true, PassageID: passages[0].PassageID, Index: 0}
+		result.Last = PassLoc{Exists: true, PassageID: passages[len(passages)-1].PassageID, Index: len(passages) - 1}
+	}
+	if o.debug() {
+		o.log("repaired Work", work, slog.Int("passages", len(passages)), slog.Int("removed", len(work.Passages)-len(passages)), slog.Int("order", order))
+	}
+	return BuildIDIndex(result), nil
+}
+
+// linkOrder returns the slice indices live of the Passages of a Work in the order their intact links give:
+// the chain from First, if First is the start of one, followed by the chains starting at the other Passages
+// without an intact Prev and at last the cycles, both in the order of the Passages slice
+func linkOrder(live []int, work Work) []int {
+	intact := func(from int, loc PassLoc, back func(Passage) PassLoc) bool {
+		if !loc.Exists || !validSlot(loc.Index, work) || work.Passages[loc.Index].PassageID != loc.PassageID {
+			return false
+		}
+		link := back(work.Passages[loc.Index])
+		return link.Exists && link.Index == from
+	}
+	prev := func(p Passage) PassLoc { return p.Prev }
+	next := func(p Passage) PassLoc { return p.Next }
+	visited := make([]bool, len(work.Passages))
+	ordered := make([]int, 0, len(live))
+	follow := func(i int) {
+		for !visited[i] {
+			visited[i] = true
+			ordered = append(ordered, i)
+			if !intact(i, work.Passages[i].Next, prev) {
+				return
+			}
+			i = work.Passages[i].Next.Index
+		}
+	}
+	first := work.First
+	if first.Exists && validSlot(first.Index, work) && work.Passages[first.Index].PassageID == first.PassageID &&
+		!intact(first.Index, work.Passages[first.Index].Prev, next) {
+		follow(first.Index)
+	}
+	for _, i := range live {
+		if !visited[i] && !intact(i, work.Passages[i].Prev, next) {
+			follow(i)
+		}
+	}
+	for _, i := range live {
+		follow(i)
+	}
+	return ordered
+}
+
+// validSlot tests whether index points to a Passage that has not been deleted
+func validSlot(index int, work Work) bool {
+	return index >= 0 && index < len(work.Passages) && work.Passages[index].PassageID != ""
+}
+
+// citationLess tests whether citation a precedes citation b, comparing level by level.
+// Levels starting with digits are compared by their number first, so that 2 precedes 10 and 10 precedes 10a,
+// and a citation precedes the citations it contains.
+func citationLess(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		na, resta := leadingNumber(a[i])
+		nb, restb := leadingNumber(b[i])
+		if na != nb {
+			return na < nb
+		}
+		return resta < restb
+	}
+	return len(a) < len(b)
+}
+
+// leadingNumber splits a citation level into the number it starts with, -1 if none, and the rest
+func leadingNumber(level string) (int, string) {
+	end := 0
+	for end < len(level) && level[end] >= '0' && level[end] <= '9' {
+		end++
+	}
+	n, err := strconv.Atoi(level[:end])
+	if err != nil {
+		return -1, level
+	}
+	return n, level[end:]
+}
@@ -0,0 +1,122 @@
+package gocite_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ThomasK81/gocite"
+)
+
+func problemKinds(report gocite.WorkReport) []string {
+	kinds := []string{}
+	for _, problem := range report.Problems {
+		kinds = append(kinds, problem.Kind)
+	}
+	return kinds
+}
+
+func TestValidateWork(t *testing.T) {
+	if report := gocite.ValidateWork(testCitationWork(t)); !report.Valid() {
+		t.Error("expected a valid work, got", report.Error())
+	}
+	tests := []struct {
+		name     string
+		damage   func(work *gocite.Work)
+		expected []string
+	}{
+		{name: "cycle", damage: func(work *gocite.Work) {
+			work.Passages[4].Next = gocite.PassLoc{Exists: true, PassageID: work.Passages[0].PassageID, Index: 0}
+			work.Passages[0].Prev = gocite.PassLoc{Exists: true, PassageID: work.Passages[4].PassageID, Index: 4}
+		}, expected: []string{gocite.ProblemFirstLast, gocite.ProblemFirstLast, gocite.ProblemCycle}},
+		{name: "dangling", damage: func(work *gocite.Work) {
+			work.Passages[1].Next.Index = 9
+		}, expected: []string{gocite.ProblemDanglingLink, gocite.ProblemBrokenLink, gocite.ProblemUnreachable, gocite.ProblemUnreachable, gocite.ProblemUnreachable}},
+		{name: "mismatched", damage: func(work *gocite.Work) {
+			work.Passages[2].Prev.PassageID = work.Passages[0].PassageID
+		}, expected: []string{gocite.ProblemMismatchedID}},
+		{name: "duplicate", damage: func(work *gocite.Work) {
+			work.Passages[3].PassageID = work.Passages[2].PassageID
+			work.Passages[2].Next.PassageID = work.Passages[2].PassageID
+			work.Passages[4].Prev.PassageID = work.Passages[2].PassageID
+		}, expected: []string{gocite.ProblemDuplicateID}},
+		{name: "first", damage: func(work *gocite.Work) {
+			work.First = gocite.PassLoc{Exists: true, PassageID: work.Passages[1].PassageID, Index: 1}
+		}, expected: []string{gocite.ProblemFirstLast, gocite.ProblemUnreachable}},
+		{name: "index", damage: func(work *gocite.Work) {
+			work.Passages[3].Index = 7
+		}, expected: []string{gocite.ProblemIndex}},
+	}
+	for _, test := range tests {
+		work := testCitationWork(t)
+		work.Passages = append([]gocite.Passage{}, work.Passages...)
+		test.damage(&work)
+		report := gocite.ValidateWork(work)
+		if kinds := problemKinds(report); !reflect.DeepEqual(kinds, test.expected) {
+			t.Error("For", test.name, "expected", test.expected, "got", kinds, report.Error())
+		}
+	}
+}
+
+func TestRepairWork(t *testing.T) {
+	work := testCitationWork(t)
+	work.Passages = []gocite.Passage{work.Passages[3], work.Passages[0], {}, work.Passages[4], work.Passages[2], work.Passages[1]}
+	work.Passages[0].Next.Index = 9
+	repaired, err := gocite.RepairWork(work, gocite.RepairBySlice)
+	if err != nil || !gocite.ValidateWork(repaired).Valid() || len(repaired.Passages) != 5 || repaired.First.PassageID != citationWork+"2.1" {
+		t.Error("unexpected repair in slice order", repaired, err)
+	}
+	repaired, err = gocite.RepairWork(work, gocite.RepairByCitation)
+	if err != nil || !gocite.ValidateWork(repaired).Valid() {
+		t.Fatal("unexpected repair in citation order", repaired, err)
+	}
+	ids := []string{}
+	for _, p := range repaired.Passages {
+		ids = append(ids, p.PassageID)
+	}
+	expected := []string{citationWork + "1.1", citationWork + "1.2", citationWork + "1.3", citationWork + "2.1", citationWork + "2.2"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Error("expected", expected, "got", ids)
+	}
+	if text, err := gocite.ExtractTextByID(citationWork+"1.3-2.1", repaired); err != nil || len(text) != 2 {
+		t.Error("expected to extract from the repaired work, got", text, err)
+	}
+	work.Passages[1].PassageID = citationWork + "2.1"
+	if _, err := gocite.RepairWork(work, gocite.RepairBySlice); err == nil {
+		t.Error("expected an error for duplicated PassageIDs")
+	}
+	if _, err := gocite.SortPassages(work); err == nil {
+		t.Error("expected SortPassages to report the broken links")
+	}
+}
+
+func TestRepairWorkByLinks(t *testing.T) {
+	work, err := gocite.InsertPassagesAfter(citationWork+"1.2", []gocite.Passage{{PassageID: citationWork + "1.2a"}}, testCitationWork(t))
+	if err != nil {
+		t.Fatal("Error calling InsertPassagesAfter: ", err)
+	}
+	inserted := []string{"1.1", "1.2", "1.2a", "1.3", "2.1", "2.2"}
+	repaired, err := gocite.RepairWork(work, gocite.RepairByLinks)
+	if err != nil {
+		t.Fatal("Error calling RepairWork: ", err)
+	}
+	checkEdit(t, "a valid Work", testCitationWork(t), repaired, inserted)
+	if !repaired.Ordered || repaired.Passages[2].PassageID != citationWork+"1.2a" {
+		t.Error("expected the repaired Passages to be stored in document order, got", repaired.Passages)
+	}
+	work.Passages = append([]gocite.Passage{}, work.Passages...)
+	work.Passages[2].Next.Index = 9
+	work.First.Index = 4
+	repaired, err = gocite.RepairWork(work, gocite.RepairByLinks)
+	if err != nil {
+		t.Fatal("Error calling RepairWork: ", err)
+	}
+	checkEdit(t, "broken links", testCitationWork(t), repaired, inserted)
+	repaired, err = gocite.RepairWork(work, gocite.RepairBySlice)
+	if err != nil {
+		t.Fatal("Error calling RepairWork: ", err)
+	}
+	checkEdit(t, "slice order", testCitationWork(t), repaired, []string{"1.1", "1.2", "1.3", "2.1", "2.2", "1.2a"})
+	if _, err := gocite.RepairWork(work, 3); err == nil {
+		t.Error("expected an error for an unknown order")
+	}
+}